@@ -5,8 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/url"
-	"os"
 	"strconv"
 
 	"github.com/pterm/pterm"
@@ -14,30 +14,50 @@ import (
 	"github.com/regix1/bazarr-sync/internal/config"
 )
 
+// Errors that tell why Bazarr could not be queried. They are wrapped, so
+// check for them with errors.Is.
+var (
+	ErrUnreachable  = errors.New("could not connect to Bazarr")
+	ErrUnauthorized = errors.New("Bazarr rejected the API token")
+)
+
+func connectionError(err error) error {
+	return fmt.Errorf("%w: %v", ErrUnreachable, err)
+}
+
+// statusError logs and returns the error for an answer other than 200.
+func statusError(endpoint string, status int) error {
+	if status == 401 || status == 403 {
+		slog.Error("Bazarr rejected the API token. Check ApiToken in the config", "endpoint", endpoint, "status", status)
+		return fmt.Errorf("%w (status %d)", ErrUnauthorized, status)
+	}
+	slog.Error("Bazarr did not answer with status 200. Are you sure the address/port are correct?", "endpoint", endpoint, "status", status)
+	return fmt.Errorf("Bazarr answered with status %d", status)
+}
+
 func QueryMovies(cfg config.Config) (movies_info, error) {
 	c := client.GetClient(cfg.ApiToken)
 	url, _ := url.JoinPath(cfg.ApiUrl, "movies")
 	resp, err := c.Get(url)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Connection Error: ", err)
-		return movies_info{}, err
+		slog.Error("Could not connect to Bazarr", "endpoint", "movies", "err", err)
+		return movies_info{}, connectionError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		fmt.Fprintln(os.Stderr, "Connection Error: Response status is not 200. Are you sure the address/port are correct?")
-		return movies_info{}, errors.New("Error: Status code not 200")
+		return movies_info{}, statusError("movies", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Reading Url Error: ", err)
+		slog.Error("Could not read Bazarr's response", "endpoint", "movies", "err", err)
 		return movies_info{}, err
 	}
 	var data movies_info
 	err = json.Unmarshal(body, &data)
 	if err != nil {
-		fmt.Println("Error in Unmarshaling json body", err)
+		slog.Error("Could not parse Bazarr's response", "endpoint", "movies", "err", err)
 		return movies_info{}, err
 	}
 	return data, nil
@@ -48,25 +68,24 @@ func QuerySeries(cfg config.Config) (shows_info, error) {
 	url, _ := url.JoinPath(cfg.ApiUrl, "series")
 	resp, err := c.Get(url)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Connection Error: ", err)
-		return shows_info{}, err
+		slog.Error("Could not connect to Bazarr", "endpoint", "series", "err", err)
+		return shows_info{}, connectionError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		fmt.Fprintln(os.Stderr, "Connection Error: Response status is not 200. Are you sure the address/port are correct?")
-		return shows_info{}, errors.New("Error: Status code not 200")
+		return shows_info{}, statusError("series", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Reading Url Error: ", err)
+		slog.Error("Could not read Bazarr's response", "endpoint", "series", "err", err)
 		return shows_info{}, err
 	}
 	var data shows_info
 	err = json.Unmarshal(body, &data)
 	if err != nil {
-		fmt.Println("Error in Unmarshaling json body", err)
+		slog.Error("Could not parse Bazarr's response", "endpoint", "series", "err", err)
 		return shows_info{}, err
 	}
 	return data, nil
@@ -82,25 +101,24 @@ func QueryEpisodes(cfg config.Config, seriesId int) (episodes_info, error) {
 
 	resp, err := c.Get(_url.String())
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Connection Error: ", err)
-		return episodes_info{}, err
+		slog.Error("Could not connect to Bazarr", "endpoint", "episodes", "err", err)
+		return episodes_info{}, connectionError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		fmt.Fprintln(os.Stderr, "Connection Error: Response status is not 200. Are you sure the address/port are correct?")
-		return episodes_info{}, errors.New("Error: Status code not 200")
+		return episodes_info{}, statusError("episodes", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Reading Url Error: ", err)
+		slog.Error("Could not read Bazarr's response", "endpoint", "episodes", "err", err)
 		return episodes_info{}, err
 	}
 	var data episodes_info
 	err = json.Unmarshal(body, &data)
 	if err != nil {
-		fmt.Println("Error in Unmarshaling json body", err)
+		slog.Error("Could not parse Bazarr's response", "endpoint", "episodes", "err", err)
 		return episodes_info{}, err
 	}
 	return data, nil
@@ -186,27 +204,29 @@ func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && (s[0:len(substr)] == substr || contains(s[1:], substr)))
 }
 
-func HealthCheck(cfg config.Config) {
+// HealthCheck prints Bazarr's version. It returns an error wrapping
+// ErrUnreachable or ErrUnauthorized when Bazarr cannot be used.
+func HealthCheck(cfg config.Config) error {
 	c := client.GetClient(cfg.ApiToken)
 	url, _ := url.JoinPath(cfg.ApiUrl, "system/status")
 	resp, err := c.Get(url)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Connection Error: ", err)
-		return
+		slog.Error("Could not connect to Bazarr", "endpoint", "system/status", "err", err)
+		return connectionError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		fmt.Fprintln(os.Stderr, "Connection Error: Response status is not 200. Are you sure the address/port are correct?")
-		return
+		return statusError("system/status", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Reading Url Error: ", err)
-		return
+		slog.Error("Could not read Bazarr's response", "endpoint", "system/status", "err", err)
+		return err
 	}
 	var data version
 	json.Unmarshal(body, &data)
 	fmt.Println("Bazarr version: ", pterm.LightBlue(data.Data.Bazarr_version))
+	return nil
 }
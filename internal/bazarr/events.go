@@ -0,0 +1,63 @@
+package bazarr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/socketio"
+)
+
+// Event is an update Bazarr pushes to its web UI, such as
+// {"type": "movie", "action": "update", "payload": 12}.
+type Event struct {
+	Type    string          `json:"type"`
+	Action  string          `json:"action"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Id returns the Radarr, Sonarr series or Sonarr episode ID the event is
+// about, when its payload is one.
+func (e Event) Id() (int, bool) {
+	var id int
+	if err := json.Unmarshal(e.Payload, &id); err == nil {
+		return id, true
+	}
+	// Some versions send the ID as a string
+	var s string
+	if err := json.Unmarshal(e.Payload, &s); err == nil {
+		if id, err := strconv.Atoi(s); err == nil {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// ListenEvents follows Bazarr's socket.io event stream until ctx is done or
+// the connection fails. onConnect is called once the stream is established.
+func ListenEvents(ctx context.Context, cfg config.Config, onConnect func(), onEvent func(Event)) error {
+	endpoint, _ := url.JoinPath(cfg.ApiUrl, "socket.io/")
+	u, _ := url.Parse(endpoint)
+	query := u.Query()
+	query.Set("apikey", cfg.ApiToken)
+	u.RawQuery = query.Encode()
+
+	c := &socketio.Client{
+		URL:    u.String(),
+		Header: http.Header{"X-Api-Key": []string{cfg.ApiToken}},
+	}
+	return c.Listen(ctx, onConnect, func(name string, args []json.RawMessage) {
+		// Bazarr emits all updates as "data" events
+		if name != "data" || len(args) == 0 {
+			return
+		}
+		var ev Event
+		if err := json.Unmarshal(args[0], &ev); err != nil {
+			return
+		}
+		onEvent(ev)
+	})
+}
@@ -0,0 +1,127 @@
+package bazarr
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/client"
+	"github.com/regix1/bazarr-sync/internal/config"
+)
+
+// Actions recorded in Bazarr's subtitle history
+const (
+	HistoryDeleted            = 0
+	HistoryDownloaded         = 1
+	HistoryManuallyDownloaded = 2
+	HistoryUpgraded           = 3
+	HistoryUploaded           = 4
+	HistorySynced             = 5
+)
+
+// Number of history entries requested per page
+const historyPageSize = 100
+
+// Time returns when Bazarr recorded the history entry. The fraction of the
+// timestamp is kept: runs start at any point of a second, and an entry from
+// the same second must still count as after the start.
+func (h history_entry) Time() time.Time {
+	return time.UnixMilli(int64(h.Raw_timestamp * 1000))
+}
+
+// IsNewSubtitle reports whether the entry put a new subtitle file on disk
+// that may need syncing. Sync entries are excluded, or every sync we make
+// would show up as new work in the next run.
+func (h history_entry) IsNewSubtitle() bool {
+	switch h.Action {
+	case HistoryDownloaded, HistoryManuallyDownloaded, HistoryUpgraded, HistoryUploaded:
+		return h.Subtitles_path != ""
+	}
+	return false
+}
+
+// Subtitle returns the subtitle the entry refers to.
+func (h history_entry) Subtitle() subtitle_info {
+	return subtitle_info{
+		Path:  h.Subtitles_path,
+		Code2: h.Language.Code2,
+	}
+}
+
+// QueryMoviesHistory returns the movie history entries recorded after since,
+// newest first.
+func QueryMoviesHistory(cfg config.Config, since time.Time) ([]movie_history_entry, error) {
+	var entries []movie_history_entry
+	for start := 0; ; start += historyPageSize {
+		var page movie_history
+		if err := queryHistoryPage(cfg, "movies/history", start, &page); err != nil {
+			return nil, err
+		}
+		for _, entry := range page.Data {
+			if !entry.Time().After(since) {
+				return entries, nil
+			}
+			entries = append(entries, entry)
+		}
+		if len(page.Data) < historyPageSize || start+historyPageSize >= page.Total {
+			return entries, nil
+		}
+	}
+}
+
+// QueryEpisodesHistory returns the episode history entries recorded after
+// since, newest first.
+func QueryEpisodesHistory(cfg config.Config, since time.Time) ([]episode_history_entry, error) {
+	var entries []episode_history_entry
+	for start := 0; ; start += historyPageSize {
+		var page episode_history
+		if err := queryHistoryPage(cfg, "episodes/history", start, &page); err != nil {
+			return nil, err
+		}
+		for _, entry := range page.Data {
+			if !entry.Time().After(since) {
+				return entries, nil
+			}
+			entries = append(entries, entry)
+		}
+		if len(page.Data) < historyPageSize || start+historyPageSize >= page.Total {
+			return entries, nil
+		}
+	}
+}
+
+func queryHistoryPage(cfg config.Config, endpoint string, start int, data any) error {
+	c := client.GetClient(cfg.ApiToken)
+	u, _ := url.JoinPath(cfg.ApiUrl, endpoint)
+	_url, _ := url.Parse(u)
+	queryUrl := _url.Query()
+	queryUrl.Set("start", strconv.Itoa(start))
+	queryUrl.Set("length", strconv.Itoa(historyPageSize))
+	_url.RawQuery = queryUrl.Encode()
+
+	resp, err := c.Get(_url.String())
+	if err != nil {
+		slog.Error("Could not connect to Bazarr", "endpoint", endpoint, "err", err)
+		return connectionError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return statusError(endpoint, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		slog.Error("Could not read Bazarr's response", "endpoint", endpoint, "err", err)
+		return err
+	}
+	err = json.Unmarshal(body, data)
+	if err != nil {
+		slog.Error("Could not parse Bazarr's response", "endpoint", endpoint, "err", err)
+		return err
+	}
+	return nil
+}
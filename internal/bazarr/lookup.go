@@ -0,0 +1,67 @@
+package bazarr
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/url"
+	"strconv"
+
+	"github.com/regix1/bazarr-sync/internal/client"
+	"github.com/regix1/bazarr-sync/internal/config"
+)
+
+// QueryMoviesById returns the movies with the given Radarr IDs.
+func QueryMoviesById(cfg config.Config, radarrIds []int) (movies_info, error) {
+	var data movies_info
+	err := queryByIds(cfg, "movies", "radarrid[]", radarrIds, &data)
+	return data, err
+}
+
+// QuerySeriesById returns the series with the given Sonarr series IDs.
+func QuerySeriesById(cfg config.Config, seriesIds []int) (shows_info, error) {
+	var data shows_info
+	err := queryByIds(cfg, "series", "seriesid[]", seriesIds, &data)
+	return data, err
+}
+
+// QueryEpisodesById returns the episodes with the given Sonarr episode IDs.
+func QueryEpisodesById(cfg config.Config, episodeIds []int) (episodes_info, error) {
+	var data episodes_info
+	err := queryByIds(cfg, "episodes", "episodeid[]", episodeIds, &data)
+	return data, err
+}
+
+func queryByIds(cfg config.Config, endpoint string, param string, ids []int, data any) error {
+	c := client.GetClient(cfg.ApiToken)
+	u, _ := url.JoinPath(cfg.ApiUrl, endpoint)
+	_url, _ := url.Parse(u)
+	queryUrl := _url.Query()
+	for _, id := range ids {
+		queryUrl.Add(param, strconv.Itoa(id))
+	}
+	_url.RawQuery = queryUrl.Encode()
+
+	resp, err := c.Get(_url.String())
+	if err != nil {
+		slog.Error("Could not connect to Bazarr", "endpoint", endpoint, "err", err)
+		return connectionError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return statusError(endpoint, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		slog.Error("Could not read Bazarr's response", "endpoint", endpoint, "err", err)
+		return err
+	}
+	err = json.Unmarshal(body, data)
+	if err != nil {
+		slog.Error("Could not parse Bazarr's response", "endpoint", endpoint, "err", err)
+		return err
+	}
+	return nil
+}
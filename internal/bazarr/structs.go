@@ -35,8 +35,11 @@ type episodes_info struct {
 
 type episode struct {
 	Title           string          `json:"title"`
+	Season          int             `json:"season"`
+	Episode         int             `json:"episode"`
 	Monitored       bool            `json:"monitored"`
 	SonarrEpisodeId int             `json:"sonarrEpisodeId"`
+	SonarrSeriesId  int             `json:"sonarrSeriesId"`
 	Subtitles       []subtitle_info `json:"subtitles"`
 }
 
@@ -48,8 +51,52 @@ type movie struct {
 	Subtitles []subtitle_info `json:"subtitles"`
 }
 
+// Subtitle is an external or embedded subtitle of a movie or episode.
+type Subtitle = subtitle_info
+
 type subtitle_info struct {
 	Path      string `json:"path"`
 	Code2     string `json:"code2"`
 	File_size int    `json:"file_size"`
 }
+
+type history_entry struct {
+	Action         int             `json:"action"`
+	Raw_timestamp  float64         `json:"raw_timestamp"`
+	Subtitles_path string          `json:"subtitles_path"`
+	Language       HistoryLanguage `json:"language"`
+	Description    string          `json:"description"`
+}
+
+// HistoryLanguage is the language of a subtitle in Bazarr's history.
+type HistoryLanguage struct {
+	Code2  string `json:"code2"`
+	Name   string `json:"name"`
+	Forced bool   `json:"forced"`
+	Hi     bool   `json:"hi"`
+}
+
+type movie_history_entry struct {
+	history_entry
+	Title    string `json:"title"`
+	RadarrId int    `json:"radarrId"`
+}
+
+type episode_history_entry struct {
+	history_entry
+	SeriesTitle     string `json:"seriesTitle"`
+	EpisodeNumber   string `json:"episode_number"`
+	EpisodeTitle    string `json:"episodeTitle"`
+	SonarrSeriesId  int    `json:"sonarrSeriesId"`
+	SonarrEpisodeId int    `json:"sonarrEpisodeId"`
+}
+
+type movie_history struct {
+	Data  []movie_history_entry `json:"data"`
+	Total int                   `json:"total"`
+}
+
+type episode_history struct {
+	Data  []episode_history_entry `json:"data"`
+	Total int                     `json:"total"`
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/bazarr"
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/socketio/socketiotest"
+)
+
+// fakeBazarr serves the parts of Bazarr's API that syncs use: one movie and
+// one show with two episodes, also looked up by ID, a history with one new
+// subtitle of each and the live event stream. Syncing a path containing
+// "already" answers that it is already in sync, one containing "fail" fails.
+type fakeBazarr struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	synced []string
+	// failSeries makes the series endpoint answer 500
+	failSeries bool
+	// File sizes of subtitles replaced since, by path
+	sizes map[string]int
+	// Bazarr's live event stream
+	events *socketiotest.Server
+}
+
+type fakeSubtitle struct {
+	Path     string `json:"path"`
+	Code2    string `json:"code2"`
+	FileSize int    `json:"file_size"`
+}
+
+// resize replaces a subtitle with one of another size.
+func (f *fakeBazarr) resize(path string, size int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sizes[path] = size
+}
+
+// subtitles returns the subtitles with their current sizes.
+func (f *fakeBazarr) subtitles(subtitles ...fakeSubtitle) []fakeSubtitle {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, sub := range subtitles {
+		if size, found := f.sizes[sub.Path]; found {
+			subtitles[i].FileSize = size
+		}
+	}
+	return subtitles
+}
+
+func newFakeBazarr(t *testing.T) *fakeBazarr {
+	f := &fakeBazarr{sizes: make(map[string]int)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/system/status", func(w http.ResponseWriter, r *http.Request) {
+		writeFake(w, map[string]any{"data": map[string]any{"bazarr_version": "1.4.0"}})
+	})
+	mux.HandleFunc("/api/movies", func(w http.ResponseWriter, r *http.Request) {
+		writeFake(w, map[string]any{"data": byIds(r, "radarrid[]", "radarrId", []map[string]any{
+			{"title": "Inception", "radarrId": 1, "subtitles": f.subtitles(
+				fakeSubtitle{"/movies/inception.en.srt", "en", 100},
+				fakeSubtitle{"/movies/inception.already.de.srt", "de", 100},
+				fakeSubtitle{"", "fr", 0}, // embedded
+			)},
+		})})
+	})
+	mux.HandleFunc("/api/series", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		fail := f.failSeries
+		f.mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeFake(w, map[string]any{"data": byIds(r, "seriesid[]", "sonarrSeriesId", []map[string]any{
+			{"title": "Dark", "sonarrSeriesId": 10},
+		})})
+	})
+	mux.HandleFunc("/api/episodes", func(w http.ResponseWriter, r *http.Request) {
+		writeFake(w, map[string]any{"data": byIds(r, "episodeid[]", "sonarrEpisodeId", []map[string]any{
+			{"title": "Secrets", "season": 1, "episode": 1, "sonarrEpisodeId": 1001, "sonarrSeriesId": 10,
+				"subtitles": f.subtitles(fakeSubtitle{"/tv/dark/s01e01.en.srt", "en", 100})},
+			{"title": "Lies", "season": 1, "episode": 2, "sonarrEpisodeId": 1002, "sonarrSeriesId": 10,
+				"subtitles": f.subtitles(fakeSubtitle{"/tv/dark/s01e02.fail.en.srt", "en", 100})},
+		})})
+	})
+	mux.HandleFunc("/api/subtitles", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+		f.mu.Lock()
+		f.synced = append(f.synced, path)
+		f.mu.Unlock()
+		switch {
+		case strings.Contains(path, "already"):
+			w.WriteHeader(http.StatusNotModified)
+		case strings.Contains(path, "fail"):
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Internal error"))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	mux.HandleFunc("/api/movies/history", func(w http.ResponseWriter, r *http.Request) {
+		writeFake(w, map[string]any{"total": 1, "data": []map[string]any{
+			{"action": bazarr.HistoryDownloaded, "raw_timestamp": f.downloaded(), "title": "Inception", "radarrId": 1,
+				"subtitles_path": "/movies/inception.en.srt", "language": map[string]any{"code2": "en"}},
+		}})
+	})
+	mux.HandleFunc("/api/episodes/history", func(w http.ResponseWriter, r *http.Request) {
+		writeFake(w, map[string]any{"total": 1, "data": []map[string]any{
+			{"action": bazarr.HistoryDownloaded, "raw_timestamp": f.downloaded(), "seriesTitle": "Dark", "episode_number": "1x01",
+				"episodeTitle": "Secrets", "sonarrSeriesId": 10, "sonarrEpisodeId": 1001,
+				"subtitles_path": "/tv/dark/s01e01.en.srt", "language": map[string]any{"code2": "en"}},
+		}})
+	})
+	f.events = socketiotest.NewServer()
+	f.events.APIKey = "token"
+	mux.Handle("/api/socket.io/", f.events)
+
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// byIds keeps the items whose key is one of the IDs in the query parameter,
+// as Bazarr does, or all of them when it is not given.
+func byIds(r *http.Request, param string, key string, items []map[string]any) []map[string]any {
+	ids := r.URL.Query()[param]
+	if len(ids) == 0 {
+		return items
+	}
+	var found []map[string]any
+	for _, item := range items {
+		if slices.Contains(ids, fmt.Sprint(item[key])) {
+			found = append(found, item)
+		}
+	}
+	return found
+}
+
+func writeFake(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// config returns a configuration that talks to the fake.
+func (f *fakeBazarr) config() config.Config {
+	return config.Config{
+		Protocol:  "http",
+		ApiToken:  "token",
+		BazarrUrl: f.URL,
+		ApiUrl:    f.URL + "/api/",
+	}
+}
+
+// downloaded is when the subtitles in the history were downloaded: a moment
+// ago, but after anything that started before the request.
+func (f *fakeBazarr) downloaded() int64 {
+	return time.Now().Add(time.Second).Unix()
+}
+
+// syncRequests returns how often each path was sent to be synced.
+func (f *fakeBazarr) syncRequests() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	requests := make(map[string]int)
+	for _, path := range f.synced {
+		requests[path]++
+	}
+	return requests
+}
@@ -0,0 +1,250 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/bazarr"
+	"github.com/regix1/bazarr-sync/internal/config"
+)
+
+// Bazarr sends several updates for one download; updates arriving within
+// this window are handled together.
+var eventBatchDelay = 5 * time.Second
+
+// Bazarr reports an update of its own after every sync. Updates arriving
+// this soon after a subtitle was synced are taken to be about that sync.
+var eventEchoWindow = time.Minute
+
+// eventFollower syncs the subtitles of movies and episodes Bazarr reports
+// as updated on its live event stream.
+type eventFollower struct {
+	s *scheduler
+
+	mu       sync.Mutex
+	movies   map[int]bool
+	episodes map[int]bool
+	timer    *time.Timer
+	handled  map[string]*handledSubtitle // by subtitle path
+}
+
+// handledSubtitle is a subtitle the follower queued or saw synced.
+type handledSubtitle struct {
+	size   int
+	runId  string    // run it is queued in, until that run finishes
+	synced time.Time // when a run last synced it
+}
+
+// followEvents keeps a subscription to Bazarr's event stream open until ctx
+// is done, reconnecting after failures.
+func (s *scheduler) followEvents(ctx context.Context) {
+	f := &eventFollower{
+		s:        s,
+		movies:   make(map[int]bool),
+		episodes: make(map[int]bool),
+		handled:  make(map[string]*handledSubtitle),
+	}
+	addRunObserver(f.observe)
+	onRunFinished(f.finished)
+
+	failing := false
+	for {
+		s.mu.Lock()
+		cfg := s.cfg
+		s.mu.Unlock()
+
+		err := bazarr.ListenEvents(ctx, cfg, func() {
+			if failing {
+				slog.Info("Reconnected to Bazarr's event stream")
+			} else {
+				slog.Info("Following Bazarr's event stream")
+			}
+			failing = false
+		}, f.receive)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if !failing {
+			slog.Warn("Lost Bazarr's event stream. Reconnecting", "every", cfg.Events.ReconnectDelay, "err", err)
+		}
+		failing = true
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cfg.Events.ReconnectDelay):
+		}
+	}
+}
+
+// receive notes the movie or episode an update is about and starts the
+// batch window.
+func (f *eventFollower) receive(ev bazarr.Event) {
+	if ev.Action != "update" {
+		return
+	}
+	id, ok := ev.Id()
+	if !ok {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch ev.Type {
+	case "movie":
+		f.movies[id] = true
+	case "episode":
+		f.episodes[id] = true
+	default:
+		return
+	}
+	if f.timer == nil {
+		f.timer = time.AfterFunc(eventBatchDelay, f.flush)
+	}
+}
+
+// observe notes when a subtitle was synced, by any run, so that Bazarr's
+// update about the sync is not taken for a new subtitle.
+func (f *eventFollower) observe(ev syncEvent) {
+	if ev.Type != eventOutcome {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h, found := f.handled[ev.Path]
+	if !found {
+		h = &handledSubtitle{size: -1}
+		f.handled[ev.Path] = h
+	}
+	h.synced = ev.Time
+	if h.runId == ev.RunId {
+		h.runId = ""
+	}
+}
+
+// finished releases the subtitles of a run that ended before syncing them.
+func (f *eventFollower) finished(run *syncRun) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, h := range f.handled {
+		if h.runId == run.ID {
+			h.runId = ""
+		}
+	}
+}
+
+// flush looks up the updated movies and episodes in Bazarr and queues a
+// sync of their external subtitles that are new or changed since they were
+// last handled.
+func (f *eventFollower) flush() {
+	f.mu.Lock()
+	movies, episodes := f.movies, f.episodes
+	f.movies, f.episodes = make(map[int]bool), make(map[int]bool)
+	f.timer = nil
+	f.mu.Unlock()
+
+	f.s.mu.Lock()
+	cfg := f.s.cfg
+	f.s.mu.Unlock()
+
+	items, err := updatedSubtitles(cfg, movies, episodes)
+	if err != nil {
+		slog.Warn("Could not look up the media Bazarr updated", "err", err)
+		return
+	}
+
+	run := newRun("event", "")
+	f.mu.Lock()
+	var fresh []historyItem
+	for _, item := range items {
+		h, seen := f.handled[item.subtitle.Path]
+		switch {
+		case !seen:
+			h = &handledSubtitle{}
+			f.handled[item.subtitle.Path] = h
+		case h.runId != "":
+			// Already waiting to be synced
+			continue
+		case h.synced.IsZero():
+			// Queued before, but that run ended without syncing it
+		case time.Since(h.synced) < eventEchoWindow || h.size == item.subtitle.File_size:
+			// Bazarr reporting our sync, or an update that left the
+			// subtitle as it was
+			h.size = item.subtitle.File_size
+			continue
+		default:
+			// Replaced under the same path, so a cache entry refers to the
+			// old subtitle
+			item.upgraded = true
+		}
+		h.size, h.runId = item.subtitle.File_size, run.ID
+		fresh = append(fresh, item)
+	}
+	f.mu.Unlock()
+
+	if len(fresh) == 0 {
+		return
+	}
+	slog.Info("Bazarr reported updated subtitles. Queueing sync.", "subtitles", len(fresh))
+	f.s.enqueue(&queuedRun{run: run, items: fresh})
+}
+
+// updatedSubtitles returns the external subtitles of the movies and
+// episodes, leaving out the kinds the schedule does not sync.
+func updatedSubtitles(cfg config.Config, movies map[int]bool, episodes map[int]bool) ([]historyItem, error) {
+	var items []historyItem
+	add := func(kind string, id int, seriesId int, media string, title string, subtitles []bazarr.Subtitle) {
+		for _, subtitle := range subtitles {
+			// Embedded subtitles cannot be synced
+			if subtitle.Path == "" || subtitle.File_size == 0 {
+				continue
+			}
+			items = append(items, historyItem{kind: kind, id: id, seriesId: seriesId, title: title, media: media,
+				subtitle: subtitle})
+		}
+	}
+
+	if len(movies) > 0 && cfg.Schedule.SyncMovies {
+		found, err := bazarr.QueryMoviesById(cfg, slices.Sorted(maps.Keys(movies)))
+		if err != nil {
+			return nil, fmt.Errorf("could not query movies: %w", err)
+		}
+		for _, movie := range found.Data {
+			add("movie", movie.RadarrId, 0, movie.Title, movie.Title, movie.Subtitles)
+		}
+	}
+
+	if len(episodes) > 0 && cfg.Schedule.SyncShows {
+		found, err := bazarr.QueryEpisodesById(cfg, slices.Sorted(maps.Keys(episodes)))
+		if err != nil {
+			return nil, fmt.Errorf("could not query episodes: %w", err)
+		}
+		seriesIds := make(map[int]bool)
+		for _, episode := range found.Data {
+			seriesIds[episode.SonarrSeriesId] = true
+		}
+		titles := make(map[int]string)
+		if len(seriesIds) > 0 {
+			series, err := bazarr.QuerySeriesById(cfg, slices.Sorted(maps.Keys(seriesIds)))
+			if err != nil {
+				return nil, fmt.Errorf("could not query series: %w", err)
+			}
+			for _, show := range series.Data {
+				titles[show.SonarrSeriesId] = show.Title
+			}
+		}
+		for _, episode := range found.Data {
+			media := titles[episode.SonarrSeriesId]
+			add("episode", episode.SonarrEpisodeId, episode.SonarrSeriesId, media,
+				fmt.Sprintf("%s - %s", media, episode.Title), episode.Subtitles)
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/server"
+)
+
+// waitJoin waits until a client joins the fake's event stream.
+func waitJoin(t *testing.T, fake *fakeBazarr) {
+	t.Helper()
+	select {
+	case <-fake.events.Joins():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the event stream was not joined")
+	}
+}
+
+// waitQueued waits for a queued run and takes it off the queue.
+func waitQueued(t *testing.T, s *scheduler) *queuedRun {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		if len(s.queue) > 0 {
+			q := s.queue[0]
+			s.queue = s.queue[1:]
+			s.mu.Unlock()
+			if q.run.Trigger != "event" {
+				t.Errorf("queued run triggered by %q, want event", q.run.Trigger)
+			}
+			return q
+		}
+		s.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no sync was queued")
+	return nil
+}
+
+// expectNothingQueued waits for a batch to pass and fails if it queued a run.
+func expectNothingQueued(t *testing.T, s *scheduler, why string) {
+	t.Helper()
+	time.Sleep(4 * eventBatchDelay)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) != 0 {
+		t.Errorf("queued %d runs for %s", len(s.queue), why)
+		s.queue = nil
+	}
+}
+
+func queuedPaths(q *queuedRun) []string {
+	var paths []string
+	for _, item := range q.items {
+		paths = append(paths, item.subtitle.Path)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// synced finishes a queued run as if every subtitle in it had been synced.
+func synced(q *queuedRun) {
+	q.run.execute([]syncPart{{Name: "history", Sync: func(run *syncRun) error {
+		for _, item := range q.items {
+			run.emit(syncEvent{Type: eventOutcome, Kind: item.kind, Id: item.id, Path: item.subtitle.Path,
+				Outcome: outcomeSuccess.String()})
+		}
+		return nil
+	}}})
+}
+
+func TestFollowEventsQueuesSyncAndReconnects(t *testing.T) {
+	defer func(delay time.Duration, echo time.Duration) {
+		eventBatchDelay, eventEchoWindow = delay, echo
+	}(eventBatchDelay, eventEchoWindow)
+	eventBatchDelay = 50 * time.Millisecond
+	eventEchoWindow = 300 * time.Millisecond
+
+	fake := newFakeBazarr(t)
+	cfg := fake.config()
+	cfg.Schedule.SyncMovies = true
+	cfg.Schedule.SyncShows = true
+	cfg.Events.ReconnectDelay = 10 * time.Millisecond
+	s := &scheduler{cfg: cfg, wake: make(chan struct{}, 1), failures: make(map[string]server.Failure)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.followEvents(ctx)
+	waitJoin(t, fake)
+
+	// Bazarr restarts: the follower connects again
+	fake.events.Drop()
+	waitJoin(t, fake)
+
+	// The movie is looked up and its external subtitles synced
+	movie := map[string]any{"type": "movie", "action": "update", "payload": 1}
+	fake.events.Emit("data", movie)
+	q := waitQueued(t, s)
+	want := []string{"/movies/inception.already.de.srt", "/movies/inception.en.srt"}
+	if paths := queuedPaths(q); !slices.Equal(paths, want) {
+		t.Fatalf("queued %v, want %v", paths, want)
+	}
+	if item := q.items[0]; item.kind != "movie" || item.id != 1 || item.media != "Inception" || item.upgraded {
+		t.Errorf("queued %+v, want a subtitle of movie 1", item)
+	}
+
+	fake.events.Emit("data", movie)
+	expectNothingQueued(t, s, "subtitles waiting to be synced")
+
+	// Bazarr reports our own sync as an update too
+	synced(q)
+	fake.events.Emit("data", movie)
+	expectNothingQueued(t, s, "the update about our sync")
+
+	time.Sleep(eventEchoWindow)
+	fake.events.Emit("data", movie)
+	expectNothingQueued(t, s, "an update that changed no subtitle")
+
+	// A subtitle replaced under the same path is synced again, even if cached
+	fake.resize("/movies/inception.en.srt", 120)
+	fake.events.Emit("data", movie)
+	q = waitQueued(t, s)
+	if len(q.items) != 1 || q.items[0].subtitle.Path != "/movies/inception.en.srt" || !q.items[0].upgraded {
+		t.Fatalf("queued %+v, want the replaced subtitle as an upgrade", q.items)
+	}
+
+	// Episode IDs may come as strings
+	fake.events.Emit("data", map[string]any{"type": "episode", "action": "update", "payload": "1001"})
+	q = waitQueued(t, s)
+	if len(q.items) != 1 {
+		t.Fatalf("queued %+v, want the subtitle of episode 1001", q.items)
+	}
+	item := q.items[0]
+	if item.kind != "episode" || item.id != 1001 || item.seriesId != 10 || item.media != "Dark" ||
+		item.title != "Dark - Secrets" || item.subtitle.Path != "/tv/dark/s01e01.en.srt" {
+		t.Errorf("queued %+v, want the subtitle of Dark - Secrets", item)
+	}
+
+	// Updates that are not about movies or episodes queue nothing
+	fake.events.Emit("data", map[string]any{"type": "movie", "action": "delete", "payload": 1})
+	fake.events.Emit("data", map[string]any{"type": "task", "action": "update", "payload": 1})
+	expectNothingQueued(t, s, "other updates")
+}
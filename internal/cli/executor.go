@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/bazarr"
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/metrics"
+)
+
+type syncOutcome int
+
+const (
+	outcomeSuccess syncOutcome = iota
+	outcomeAlreadySynced
+	outcomeFailed
+)
+
+func (o syncOutcome) String() string {
+	switch o {
+	case outcomeSuccess:
+		return "success"
+	case outcomeAlreadySynced:
+		return "already_synced"
+	default:
+		return "failed"
+	}
+}
+
+func parseOutcome(s string) syncOutcome {
+	switch s {
+	case "success":
+		return outcomeSuccess
+	case "already_synced":
+		return outcomeAlreadySynced
+	default:
+		return outcomeFailed
+	}
+}
+
+// syncSummary counts the outcomes of a sync pass.
+type syncSummary struct {
+	Success       int `json:"success"`
+	AlreadySynced int `json:"already_synced"`
+	Skipped       int `json:"skipped"`
+	Failed        int `json:"failed"`
+}
+
+func (s *syncSummary) record(outcome syncOutcome) {
+	switch outcome {
+	case outcomeSuccess:
+		s.Success++
+	case outcomeAlreadySynced:
+		s.AlreadySynced++
+	default:
+		s.Failed++
+	}
+}
+
+func (s *syncSummary) add(other syncSummary) {
+	s.Success += other.Success
+	s.AlreadySynced += other.AlreadySynced
+	s.Skipped += other.Skipped
+	s.Failed += other.Failed
+}
+
+func (s syncSummary) print() {
+	fmt.Println(strings.Repeat("-", 60))
+	fmt.Printf("Sync completed:\n")
+	fmt.Printf("  ✅ %d newly synced\n", s.Success)
+	fmt.Printf("  ✓  %d already in sync\n", s.AlreadySynced)
+	fmt.Printf("  ⏭️  %d skipped (cached/embedded)\n", s.Skipped)
+	fmt.Printf("  ❌ %d failed\n", s.Failed)
+
+	if s.Failed > 0 && !verbose {
+		fmt.Println("\n💡 Tip: Run with --verbose to see detailed error messages")
+	}
+}
+
+// selection narrows a library pass to specific media.
+type selection struct {
+	// Radarr movie IDs or Sonarr series IDs, empty for all
+	Ids []int
+	// Radarr movie ID or Sonarr episode ID to continue from, -1 to start at the beginning
+	ContinueFrom int
+	Filter       *downloadFilter
+}
+
+// allSelection selects the whole library.
+var allSelection = selection{ContinueFrom: -1}
+
+func (s selection) includes(id int) bool {
+	if len(s.Ids) == 0 {
+		return true
+	}
+	for _, selected := range s.Ids {
+		if selected == id {
+			return true
+		}
+	}
+	return false
+}
+
+// newSyncParams builds the sync request for a subtitle with the configured
+// sync options applied.
+func newSyncParams(cfg config.Config, ref subtitleRef) bazarr.Sync_params {
+	params := bazarr.GetSyncParams(ref.Kind, ref.Id, bazarr.Subtitle{Path: ref.Path, Code2: ref.Language})
+	if cfg.SyncOptions.GoldenSection {
+		params.Gss = "True"
+	}
+	if cfg.SyncOptions.NoFramerateFix {
+		params.No_framerate_fix = "True"
+	}
+	return params
+}
+
+type syncResult struct {
+	success bool
+	message string
+}
+
+// syncSubtitle asks Bazarr to sync one subtitle, retrying once on a real
+// failure. The outcome is reported to the run with the number of retries.
+func syncSubtitle(run *syncRun, cfg config.Config, ref subtitleRef) syncOutcome {
+	params := newSyncParams(cfg, ref)
+	outcome, message, retries := syncWithRetry(run, cfg, ref, params)
+	run.emit(syncEvent{Type: eventOutcome, Kind: ref.Kind, Id: ref.Id, Title: ref.Title, Media: ref.Media,
+		Language: ref.Language, Path: ref.Path, Outcome: outcome.String(), Message: message, Retries: retries})
+	return outcome
+}
+
+func syncWithRetry(run *syncRun, cfg config.Config, ref subtitleRef, params bazarr.Sync_params) (syncOutcome, string, int) {
+	run.emit(syncEvent{Type: eventSyncing, Kind: ref.Kind, Id: ref.Id, Title: ref.Title, Media: ref.Media,
+		Language: ref.Language, Path: ref.Path})
+
+	result := timedSync(cfg, params)
+	if outcome, ok := result.outcome(); ok {
+		return outcome, result.message, 0
+	}
+
+	// Retry once for real failures
+	run.emit(syncEvent{Type: eventRetry, Kind: ref.Kind, Id: ref.Id, Title: ref.Title, Media: ref.Media,
+		Language: ref.Language, Path: ref.Path, Message: result.message})
+
+	time.Sleep(2 * time.Second)
+	result = timedSync(cfg, params)
+	outcome, _ := result.outcome()
+	return outcome, result.message, 1
+}
+
+// outcome returns the outcome of a sync attempt, and false for a failure
+// that is worth a retry.
+func (r syncResult) outcome() (syncOutcome, bool) {
+	switch {
+	case r.success:
+		return outcomeSuccess, true
+	case isAlreadySynced(r.message):
+		return outcomeAlreadySynced, true
+	}
+	return outcomeFailed, false
+}
+
+// timedSync asks Bazarr to sync a subtitle and records how long it took.
+func timedSync(cfg config.Config, params bazarr.Sync_params) syncResult {
+	done := metrics.SyncStarted()
+	ok, msg := bazarr.Sync(cfg, params)
+
+	outcome := outcomeFailed
+	if ok {
+		outcome = outcomeSuccess
+	} else if isAlreadySynced(msg) {
+		outcome = outcomeAlreadySynced
+	}
+	done(outcome.String())
+	return syncResult{ok, msg}
+}
+
+// isAlreadySynced reports whether a failed sync message means the subtitle
+// needed no changes.
+func isAlreadySynced(message string) bool {
+	return strings.Contains(strings.ToLower(message), "already") ||
+		strings.Contains(strings.ToLower(message), "sync") ||
+		strings.Contains(message, "304") ||
+		strings.Contains(message, "409")
+}
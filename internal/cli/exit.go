@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/regix1/bazarr-sync/internal/bazarr"
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/logging"
+)
+
+// Exit codes, documented in the README. Scripts and CronJobs rely on them,
+// so they keep their meaning.
+const (
+	exitOK         = 0 // every subtitle synced or was already in sync
+	exitError      = 1 // unexpected error or invalid flags
+	exitConfig     = 2 // the config file is missing or invalid
+	exitConnection = 3 // Bazarr could not be reached or rejected the API token
+	exitPartial    = 4 // more subtitles failed than --fail-on allows
+	exitAllFailed  = 5 // every subtitle that was tried failed
+	exitCancelled  = 6 // the run was cancelled
+)
+
+// Results of a run, as given in the JSON output next to the exit code
+var exitResults = map[int]string{
+	exitOK:         "success",
+	exitError:      "error",
+	exitConfig:     "config_error",
+	exitConnection: "connection_error",
+	exitPartial:    "partial_failure",
+	exitAllFailed:  "all_failed",
+	exitCancelled:  "cancelled",
+}
+
+// --fail-on
+var failOnFlag string
+var failOn failThreshold
+
+// failThreshold is how many failed subtitles a run tolerates before it
+// counts as failed: a number, or a percentage of the subtitles tried.
+type failThreshold struct {
+	limit   float64
+	percent bool
+}
+
+func parseFailThreshold(s string) (failThreshold, error) {
+	t := failThreshold{}
+	value := s
+	if strings.HasSuffix(s, "%") {
+		t.percent = true
+		value = strings.TrimSuffix(s, "%")
+	}
+	limit, err := strconv.ParseFloat(value, 64)
+	if err != nil || limit < 0 || (t.percent && limit > 100) {
+		return t, fmt.Errorf("--fail-on must be a number of subtitles (3) or a percentage (5%%), got %q", s)
+	}
+	t.limit = limit
+	return t, nil
+}
+
+// exceeded reports whether failed out of tried subtitles is more than the
+// threshold allows.
+func (t failThreshold) exceeded(failed int, tried int) bool {
+	if failed == 0 {
+		return false
+	}
+	if t.percent {
+		return float64(failed)*100/float64(tried) > t.limit
+	}
+	return float64(failed) > t.limit
+}
+
+// setupExit checks --fail-on.
+func setupExit() error {
+	var err error
+	failOn, err = parseFailThreshold(failOnFlag)
+	return err
+}
+
+// runExitCode tells how a finished run went, as an exit code.
+func runExitCode(run *syncRun) int {
+	if run.Cancelled() {
+		return exitCancelled
+	}
+	for _, part := range run.Parts {
+		if part.Err != nil && isConnectionError(part.Err) {
+			return exitConnection
+		}
+	}
+	if run.Failed() {
+		return exitError
+	}
+
+	summary := run.Summary
+	tried := summary.Success + summary.AlreadySynced + summary.Failed
+	switch {
+	case !failOn.exceeded(summary.Failed, tried):
+		return exitOK
+	case summary.Failed == tried:
+		return exitAllFailed
+	}
+	return exitPartial
+}
+
+// errorExitCode returns the exit code for an error that stopped a command
+// before it could sync.
+func errorExitCode(err error) int {
+	if isConnectionError(err) {
+		return exitConnection
+	}
+	return exitError
+}
+
+func isConnectionError(err error) bool {
+	return errors.Is(err, bazarr.ErrUnreachable) || errors.Is(err, bazarr.ErrUnauthorized)
+}
+
+// checkConfig ends the process when the configuration is invalid.
+func checkConfig(cfg config.Config) {
+	if err := config.Validate(cfg); err != nil {
+		slog.Error("Invalid configuration", "err", err)
+		exit(exitConfig)
+	}
+}
+
+// exit ends the process with code, closing the log file first.
+func exit(code int) {
+	logging.Close()
+	os.Exit(code)
+}
+
+// exitForRun ends the process unless the run succeeded.
+func exitForRun(run *syncRun) {
+	if code := runExitCode(run); code != exitOK {
+		exit(code)
+	}
+}
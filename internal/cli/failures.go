@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/server"
+)
+
+// recordOutcome keeps the failures shown on the dashboard current: a failed
+// sync is recorded and a later successful one clears it again.
+func (s *scheduler) recordOutcome(ev syncEvent) {
+	if ev.Type != eventOutcome {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if parseOutcome(ev.Outcome) != outcomeFailed {
+		delete(s.failures, ev.Path)
+		return
+	}
+	s.failures[ev.Path] = server.Failure{
+		Kind:     ev.Kind,
+		Id:       ev.Id,
+		Title:    ev.Title,
+		Language: ev.Language,
+		Path:     ev.Path,
+		Message:  ev.Message,
+		RunId:    ev.RunId,
+		Time:     ev.Time,
+	}
+}
+
+// Failures implements server.Controller.
+func (s *scheduler) Failures() []server.Failure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures := []server.Failure{}
+	for _, failure := range s.failures {
+		failures = append(failures, failure)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Time.After(failures[j].Time)
+	})
+	return failures
+}
+
+// RetryFailure implements server.Controller.
+func (s *scheduler) RetryFailure(path string) (string, error) {
+	s.mu.Lock()
+	failure, found := s.failures[path]
+	s.mu.Unlock()
+
+	if !found {
+		return "", fmt.Errorf("%w: %s", server.ErrUnknownFailure, path)
+	}
+	ref := subtitleRef{Kind: failure.Kind, Id: failure.Id, Title: failure.Title,
+		Language: failure.Language, Path: failure.Path}
+	run := newRun("retry", "")
+	run.RetryOf = failure.RunId
+	return s.enqueue(&queuedRun{run: run, retry: &ref}), nil
+}
+
+// Cache implements server.Controller.
+func (s *scheduler) Cache() server.CacheContents {
+	s.mu.Lock()
+	enabled := s.cfg.Cache.Enabled
+	s.mu.Unlock()
+
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+
+	return server.CacheContents{
+		Enabled: enabled,
+		Movies:  sortedKeys(movies_cache),
+		Shows:   sortedKeys(shows_cache),
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// retryPart syncs subtitles again, ignoring the cache.
+func retryPart(cfg config.Config, refs ...subtitleRef) syncPart {
+	opts := partOptions(cfg, allSelection)
+	opts.Cache = false
+	return syncPart{
+		Name: "retry",
+		Sync: func(run *syncRun) error {
+			for i, ref := range refs {
+				if err := run.stopped(); err != nil {
+					return err
+				}
+
+				run.item(ref.Kind, ref.Id, ref.Title, i+1, len(refs))
+
+				outcome := syncSubtitle(run, cfg, ref)
+				if outcome != outcomeFailed {
+					if ref.Kind == "episode" {
+						Write_shows_cache(cfg, ref.Path)
+					} else {
+						Write_movies_cache(cfg, ref.Path)
+					}
+				}
+			}
+			return nil
+		},
+		Options: opts,
+	}
+}
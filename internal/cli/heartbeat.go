@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/notify"
+)
+
+// How long a heartbeat service gets to answer a ping
+const heartbeatTimeout = 10 * time.Second
+
+// pingHeartbeats sends a signal to the heartbeat checks of a scheduled job.
+// A ping that fails or times out is only logged and never affects the run.
+func pingHeartbeats(cfg config.Config, job string, signal string, message string) {
+	for _, hb := range cfg.Schedule.Heartbeats {
+		if hb.Job != job {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), heartbeatTimeout)
+		err := notify.Ping(ctx, hb, signal, message)
+		cancel()
+		if err != nil {
+			slog.Warn("Heartbeat ping failed", "job", job, "signal", signal, "err", err)
+		}
+	}
+}
+
+// heartbeatResult returns the signal for a finished run and a short summary
+// to send with it. Runs that did not complete, including cancelled ones,
+// report a failure.
+func heartbeatResult(run *syncRun) (string, string) {
+	snap := run.snapshot()
+	lines := []string{fmt.Sprintf("%d synced, %d already in sync, %d skipped, %d failed in %s",
+		snap.Summary.Success, snap.Summary.AlreadySynced, snap.Summary.Skipped, snap.Summary.Failed,
+		snap.Finished.Sub(snap.Started).Round(time.Second))}
+	for _, part := range snap.Parts {
+		if part.Err != nil && !errors.Is(part.Err, context.Canceled) {
+			lines = append(lines, fmt.Sprintf("%s did not complete: %v", part.Name, part.Err))
+		}
+	}
+
+	signal := notify.PingSuccess
+	switch {
+	case run.Cancelled():
+		signal = notify.PingFail
+		lines = append(lines, "cancelled")
+	case run.Failed():
+		signal = notify.PingFail
+	}
+	return signal, strings.Join(lines, "\n")
+}
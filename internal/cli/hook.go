@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/bazarr"
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var hookSubtitle string
+var hookLanguage string
+var hookEpisodeId string
+var hookSeriesId string
+var hookMovieId int
+var hookAsync bool
+var hookDaemonUrl string
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Sync one subtitle, for use as Bazarr's custom post-processing command",
+	Example: `  bazarr-sync hook --subtitle "{{subtitles}}" --lang "{{subtitles_language_code2}}" --episode-id "{{episode_id}}" --series-id "{{series_id}}"
+  bazarr-sync hook --subtitle "{{subtitles}}" --lang "{{subtitles_language_code2}}" --episode-id "{{episode_id}}" --series-id "{{series_id}}" --async`,
+	Long: `Syncs a single subtitle right after Bazarr downloaded it. Enable "Use Custom Post-Processing"
+in Bazarr's Subtitles settings and use one of the examples as the command.
+
+Bazarr passes an empty {{series_id}} for movies and the Radarr ID as {{episode_id}}, so the
+same command works for movies and episodes. --movie-id can be used instead for movies.
+
+Bazarr waits for the command to finish. With --async the subtitle is handed to a running
+bazarr-sync daemon (Server.Listen in the config) and the hook returns immediately; the
+daemon then syncs it with its own configuration.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.GetConfig()
+
+		// Override config with command line flags
+		applyFlagOverrides(cmd, &cfg)
+		checkConfig(cfg)
+
+		episodeId, err := optionalId("--episode-id", hookEpisodeId)
+		if err != nil {
+			slog.Error("Invalid hook arguments", "err", err)
+			exit(exitError)
+		}
+		seriesId, err := optionalId("--series-id", hookSeriesId)
+		if err != nil {
+			slog.Error("Invalid hook arguments", "err", err)
+			exit(exitError)
+		}
+
+		req := server.SubtitleRequest{Kind: "episode", Id: episodeId, SeriesId: seriesId,
+			Language: hookLanguage, Path: hookSubtitle}
+		if hookMovieId != 0 || seriesId == 0 {
+			req.Kind = "movie"
+			req.Id = hookMovieId
+			if req.Id == 0 {
+				req.Id = episodeId
+			}
+		}
+		item, err := subtitleItem(req)
+		if err != nil {
+			slog.Error("Invalid hook arguments", "err", err)
+			exit(exitError)
+		}
+
+		if hookAsync {
+			url := hookDaemonUrl
+			if url == "" {
+				url = daemonUrl(cfg)
+			}
+			runId, err := handOff(url, cfg.Server.Token, req)
+			if err != nil {
+				slog.Error("Could not hand the subtitle to the daemon", "url", url, "err", err)
+				exit(exitError)
+			}
+			fmt.Printf("Queued sync of %s on %s (run %s)\n", req.Path, url, runId)
+			return
+		}
+
+		if cfg.Cache.Enabled {
+			Load_cache(cfg)
+		}
+		run := executeRun("hook", []syncPart{historyItemsPart(cfg, []historyItem{item})})
+		exitForRun(run)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(hookCmd)
+	hookCmd.Flags().StringVar(&hookSubtitle, "subtitle", "", "Path of the subtitle file ({{subtitles}})")
+	hookCmd.Flags().StringVar(&hookLanguage, "lang", "", "Two letter language code of the subtitle ({{subtitles_language_code2}})")
+	hookCmd.Flags().StringVar(&hookEpisodeId, "episode-id", "", "Sonarr episode ID, or Radarr movie ID when --series-id is empty ({{episode_id}})")
+	hookCmd.Flags().StringVar(&hookSeriesId, "series-id", "", "Sonarr series ID, empty for movies ({{series_id}})")
+	hookCmd.Flags().IntVar(&hookMovieId, "movie-id", 0, "Radarr movie ID")
+	hookCmd.Flags().BoolVar(&hookAsync, "async", false, "Hand the sync to a running daemon and return immediately")
+	hookCmd.Flags().StringVar(&hookDaemonUrl, "daemon-url", "", "Address of the daemon's control API (default from Server.Listen)")
+	hookCmd.Flags().BoolVar(&verbose, "verbose", false, "Show detailed error messages")
+	hookCmd.MarkFlagRequired("subtitle")
+	hookCmd.MarkFlagRequired("lang")
+}
+
+// optionalId parses an ID flag. Bazarr fills placeholders it has no value
+// for with an empty string, which counts as no ID.
+func optionalId(flag string, value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "None" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number, got %q", flag, value)
+	}
+	return id, nil
+}
+
+// subtitleItem checks a single-subtitle request and turns it into an item
+// that can be synced like one found in Bazarr's history.
+func subtitleItem(req server.SubtitleRequest) (historyItem, error) {
+	if req.Kind != "movie" && req.Kind != "episode" {
+		return historyItem{}, fmt.Errorf("%w: kind must be movie or episode, got %q", server.ErrInvalidRequest, req.Kind)
+	}
+	if req.Id <= 0 {
+		return historyItem{}, fmt.Errorf("%w: a Radarr movie ID or Sonarr episode ID is required", server.ErrInvalidRequest)
+	}
+	if req.Path == "" || req.Language == "" {
+		return historyItem{}, fmt.Errorf("%w: subtitle path and language are required", server.ErrInvalidRequest)
+	}
+	return historyItem{
+		kind:     req.Kind,
+		id:       req.Id,
+		seriesId: req.SeriesId,
+		title:    filepath.Base(req.Path),
+		subtitle: bazarr.Subtitle{Path: req.Path, Code2: req.Language},
+	}, nil
+}
+
+// daemonUrl derives the address of the local daemon's control API from
+// Server.Listen.
+func daemonUrl(cfg config.Config) string {
+	listen := cfg.Server.Listen
+	if listen == "" {
+		listen = ":8080"
+	}
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "http://" + listen
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// handOff queues the subtitle on the daemon and returns the run ID.
+func handOff(url string, token string, req server.SubtitleRequest) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	httpReq, err := http.NewRequest("POST", strings.TrimSuffix(url, "/")+"/api/subtitles", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	httpClient := http.Client{Timeout: 10 * time.Second}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		RunId string `json:"run_id"`
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusAccepted {
+		if result.Error == "" {
+			result.Error = resp.Status
+		}
+		return "", fmt.Errorf("daemon answered %d: %s", resp.StatusCode, result.Error)
+	}
+	return result.RunId, nil
+}
@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/bazarr"
+	"github.com/regix1/bazarr-sync/internal/config"
+)
+
+// historyItem is a subtitle found in Bazarr's history that needs syncing.
+type historyItem struct {
+	kind       string // "movie" or "episode", as used by the sync API
+	id         int
+	seriesId   int // Sonarr series of an episode
+	title      string
+	media      string // movie or series title
+	subtitle   bazarr.Subtitle
+	upgraded   bool
+	downloaded time.Time
+}
+
+// queryHistoryItems collects the subtitles Bazarr downloaded, upgraded or had
+// uploaded after since and, unless until is zero, no later than until. A
+// subtitle that appears several times is returned once.
+func queryHistoryItems(cfg config.Config, since time.Time, until time.Time, movies bool, shows bool) ([]historyItem, error) {
+	var items []historyItem
+	seen := make(map[string]bool)
+	add := func(item historyItem) {
+		if !until.IsZero() && item.downloaded.After(until) {
+			return
+		}
+		if seen[item.subtitle.Path] {
+			return
+		}
+		seen[item.subtitle.Path] = true
+		items = append(items, item)
+	}
+
+	if shows {
+		entries, err := bazarr.QueryEpisodesHistory(cfg, since)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsNewSubtitle() {
+				continue
+			}
+			add(historyItem{
+				kind:       "episode",
+				id:         entry.SonarrEpisodeId,
+				seriesId:   entry.SonarrSeriesId,
+				title:      fmt.Sprintf("%s %s - %s", entry.SeriesTitle, entry.EpisodeNumber, entry.EpisodeTitle),
+				media:      entry.SeriesTitle,
+				subtitle:   entry.Subtitle(),
+				upgraded:   entry.Action == bazarr.HistoryUpgraded,
+				downloaded: entry.Time(),
+			})
+		}
+	}
+
+	if movies {
+		entries, err := bazarr.QueryMoviesHistory(cfg, since)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsNewSubtitle() {
+				continue
+			}
+			add(historyItem{
+				kind:       "movie",
+				id:         entry.RadarrId,
+				title:      entry.Title,
+				media:      entry.Title,
+				subtitle:   entry.Subtitle(),
+				upgraded:   entry.Action == bazarr.HistoryUpgraded,
+				downloaded: entry.Time(),
+			})
+		}
+	}
+
+	return items, nil
+}
+
+// sync_history syncs only the subtitles Bazarr recorded in its history after
+// since, instead of walking the whole library.
+func sync_history(run *syncRun, cfg config.Config, since time.Time) error {
+	items, err := queryHistoryItems(cfg, since, time.Time{}, cfg.Schedule.SyncMovies, cfg.Schedule.SyncShows)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Found %d new subtitles in Bazarr's history since %s.\n",
+		len(items), since.Format("2006-01-02 15:04:05 MST"))
+	fmt.Println(strings.Repeat("-", 60))
+
+	return syncHistoryItems(run, cfg, items)
+}
+
+// historyItemsPart syncs a list of history items that was queried earlier.
+func historyItemsPart(cfg config.Config, items []historyItem) syncPart {
+	return syncPart{
+		Name: "history",
+		Sync: func(run *syncRun) error {
+			return syncHistoryItems(run, cfg, items)
+		},
+		Options: partOptions(cfg, allSelection),
+	}
+}
+
+// syncHistoryItems syncs history items one after another, skipping the ones
+// already in the cache.
+func syncHistoryItems(run *syncRun, cfg config.Config, items []historyItem) error {
+	for i, item := range items {
+		if err := run.stopped(); err != nil {
+			return err
+		}
+
+		run.item(item.kind, item.id, item.title, i+1, len(items))
+
+		cache, writeCache := movies_cache, Write_movies_cache
+		if item.kind == "episode" {
+			cache, writeCache = shows_cache, Write_shows_cache
+		}
+		ref := subtitleRef{Kind: item.kind, Id: item.id, Title: item.title, Media: item.media,
+			Language: item.subtitle.Code2, Path: item.subtitle.Path}
+
+		// An upgrade replaces the file under the same path, so a cache entry
+		// for it refers to the old subtitle
+		if cfg.Cache.Enabled && cache[item.subtitle.Path] && !item.upgraded {
+			run.skip(ref, "cached")
+			continue
+		}
+
+		outcome := syncSubtitle(run, cfg, ref)
+		if outcome != outcomeFailed {
+			writeCache(cfg, item.subtitle.Path)
+		}
+
+		// Add delay between syncs to avoid overwhelming Bazarr
+		time.Sleep(1 * time.Second)
+	}
+	return nil
+}
@@ -0,0 +1,343 @@
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/regix1/bazarr-sync/internal/bazarr"
+)
+
+// --list options of the sync commands
+var listColumns []string
+var listSort string
+var listSearch string
+var listFormat string
+
+var listOpts listOptions
+
+// listOut is where --format csv writes, the real stdout
+var listOut io.Writer = os.Stdout
+
+// Optional columns of --list, in the order they are shown
+var listColumnNames = []string{"languages", "subtitles", "status", "monitored", "imdb"}
+
+// Sync status of a listed item, from its external subtitles
+const (
+	listSynced      = "synced"
+	listPartial     = "partial"
+	listFailed      = "failed"
+	listNeverSynced = "never_synced"
+)
+
+// listOptions are the checked --list flags.
+type listOptions struct {
+	columns map[string]bool
+	sort    string
+	search  string
+	format  string
+}
+
+// setupList checks the --list flags. With --format json or csv, everything
+// meant for people is written to stderr, as with --output json.
+func setupList() error {
+	if !to_list {
+		return nil
+	}
+	var err error
+	listOpts, err = newListOptions()
+	if err != nil {
+		return err
+	}
+	switch {
+	case listOpts.format == "json" && outputFormat == outputText:
+		outputFormat = outputJSON
+	case listOpts.format == "csv":
+		listOut = divertHumanOutput()
+	}
+	return nil
+}
+
+func newListOptions() (listOptions, error) {
+	opts := listOptions{columns: make(map[string]bool), sort: listSort, search: listSearch, format: listFormat}
+	for _, column := range listColumns {
+		switch {
+		case column == "all":
+			for _, name := range listColumnNames {
+				opts.columns[name] = true
+			}
+		case slices.Contains(listColumnNames, column):
+			opts.columns[column] = true
+		default:
+			return opts, fmt.Errorf("--columns must be all or any of %s, got %q", strings.Join(listColumnNames, ", "), column)
+		}
+	}
+
+	switch listSort {
+	case "", "title", "id", "subtitles", "status":
+	default:
+		return opts, fmt.Errorf("--sort must be title, id, subtitles or status, got %q", listSort)
+	}
+	if (listSort == "subtitles" || listSort == "status") && !opts.columns[listSort] {
+		// Sorting by them needs them looked up
+		opts.columns[listSort] = true
+	}
+
+	switch listFormat {
+	case "":
+		opts.format = "table"
+		if outputFormat != outputText {
+			opts.format = "json"
+		}
+	case "table", "csv":
+		if outputFormat != outputText {
+			return opts, fmt.Errorf("--format %s can't be combined with --output %s", listFormat, outputFormat)
+		}
+	case "json":
+	default:
+		return opts, fmt.Errorf("--format must be table, json or csv, got %q", listFormat)
+	}
+	return opts, nil
+}
+
+// subtitles reports whether the listed items need their subtitles.
+func (o listOptions) subtitles() bool {
+	return o.columns["languages"] || o.columns["subtitles"] || o.columns["status"]
+}
+
+// listedItem is a movie, show or episode printed by --list. The optional
+// fields are only set when their column was asked for.
+type listedItem struct {
+	Title           string   `json:"title"`
+	Series          string   `json:"series,omitempty"`
+	RadarrId        int      `json:"radarr_id,omitempty"`
+	SonarrSeriesId  int      `json:"sonarr_series_id,omitempty"`
+	SonarrEpisodeId int      `json:"sonarr_episode_id,omitempty"`
+	Season          *int     `json:"season,omitempty"`
+	Episode         *int     `json:"episode,omitempty"`
+	Languages       []string `json:"languages,omitempty"`
+	Subtitles       *int     `json:"subtitles,omitempty"`
+	Status          string   `json:"status,omitempty"`
+	Monitored       *bool    `json:"monitored,omitempty"`
+	ImdbId          string   `json:"imdb_id,omitempty"`
+
+	// Sync states of the external subtitles, for the status
+	synced, failed, never int
+}
+
+func (i listedItem) id() int {
+	switch {
+	case i.SonarrEpisodeId != 0:
+		return i.SonarrEpisodeId
+	case i.SonarrSeriesId != 0:
+		return i.SonarrSeriesId
+	}
+	return i.RadarrId
+}
+
+// addSubtitles counts the external subtitles of the item, or of one of the
+// episodes of a show.
+func (i *listedItem) addSubtitles(subtitles []bazarr.Subtitle, states syncStates) {
+	for _, sub := range subtitles {
+		if sub.Path == "" || sub.File_size == 0 {
+			continue
+		}
+		if !slices.Contains(i.Languages, sub.Code2) {
+			i.Languages = append(i.Languages, sub.Code2)
+		}
+		switch states.of(sub.Path) {
+		case stateSynced:
+			i.synced++
+		case stateFailed:
+			i.failed++
+		default:
+			i.never++
+		}
+	}
+}
+
+// setColumns fills in the optional columns once all subtitles were added.
+func (i *listedItem) setColumns(opts listOptions, monitored bool, imdbId string) {
+	if opts.columns["languages"] {
+		sort.Strings(i.Languages)
+	} else {
+		i.Languages = nil
+	}
+	if opts.columns["subtitles"] {
+		count := i.synced + i.failed + i.never
+		i.Subtitles = &count
+	}
+	if opts.columns["status"] {
+		switch {
+		case i.failed > 0:
+			i.Status = listFailed
+		case i.synced > 0 && i.never > 0:
+			i.Status = listPartial
+		case i.synced > 0:
+			i.Status = listSynced
+		case i.never > 0:
+			i.Status = listNeverSynced
+		}
+	}
+	if opts.columns["monitored"] {
+		i.Monitored = &monitored
+	}
+	if opts.columns["imdb"] {
+		i.ImdbId = imdbId
+	}
+}
+
+// fuzzyMatch reports whether all letters and digits of query appear in title
+// in the same order, ignoring case: "brba" matches "Breaking Bad".
+func fuzzyMatch(query string, title string) bool {
+	remaining := []rune(strings.ToLower(title))
+	for _, r := range strings.ToLower(query) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			continue
+		}
+		i := slices.Index(remaining, r)
+		if i < 0 {
+			return false
+		}
+		remaining = remaining[i+1:]
+	}
+	return true
+}
+
+// Order of the statuses with --sort status: those needing attention first
+var listStatusOrder = map[string]int{listFailed: 0, listNeverSynced: 1, listPartial: 2, listSynced: 3, "": 4}
+
+// filterList applies --search and --sort to the listed items. Without
+// --sort, items keep Bazarr's order.
+func filterList(items []listedItem, opts listOptions) []listedItem {
+	if opts.search != "" {
+		items = slices.DeleteFunc(items, func(i listedItem) bool {
+			return !fuzzyMatch(opts.search, i.Title)
+		})
+	}
+
+	switch opts.sort {
+	case "title":
+		sort.SliceStable(items, func(a, b int) bool {
+			return strings.ToLower(items[a].Title) < strings.ToLower(items[b].Title)
+		})
+	case "id":
+		sort.SliceStable(items, func(a, b int) bool { return items[a].id() < items[b].id() })
+	case "subtitles":
+		sort.SliceStable(items, func(a, b int) bool { return *items[a].Subtitles > *items[b].Subtitles })
+	case "status":
+		sort.SliceStable(items, func(a, b int) bool {
+			return listStatusOrder[items[a].Status] < listStatusOrder[items[b].Status]
+		})
+	}
+	return items
+}
+
+// printList writes the listed items in the --format chosen. idName is the
+// CSV name of the ID column, such as radarr_id.
+func printList(items []listedItem, opts listOptions, idName string, noun string) {
+	switch opts.format {
+	case "json":
+		writeList(items)
+	case "csv":
+		header, rows := listColumnsOf(items, opts, idName)
+		w := csv.NewWriter(listOut)
+		w.Write(header)
+		w.WriteAll(rows)
+	default:
+		writeListTable(items, opts, idName)
+		fmt.Printf("\nTotal: %d %s\n", len(items), noun)
+	}
+}
+
+// Table headings of the --list columns, by CSV name
+var listHeadings = map[string]string{
+	"title":             "Title",
+	"radarr_id":         "RadarrId",
+	"sonarr_series_id":  "SonarrSeriesId",
+	"sonarr_episode_id": "SonarrEpisodeId",
+	"series":            "Series",
+	"season":            "Season",
+	"episode":           "Episode",
+	"languages":         "Languages",
+	"subtitles":         "Subtitles",
+	"status":            "Status",
+	"monitored":         "Monitored",
+	"imdb_id":           "IMDb",
+}
+
+// listColumnsOf returns the CSV header and the cells of every item.
+func listColumnsOf(items []listedItem, opts listOptions, idName string) ([]string, [][]string) {
+	episodes := len(items) > 0 && items[0].Season != nil
+	header := []string{"title", idName}
+	if episodes {
+		header = append(header, "series", "season", "episode")
+	}
+	for _, name := range listColumnNames {
+		if !opts.columns[name] {
+			continue
+		}
+		if name == "imdb" {
+			name = "imdb_id"
+		}
+		header = append(header, name)
+	}
+
+	var rows [][]string
+	for _, item := range items {
+		row := []string{item.Title, strconv.Itoa(item.id())}
+		if episodes {
+			row = append(row, item.Series, strconv.Itoa(*item.Season), strconv.Itoa(*item.Episode))
+		}
+		for _, name := range listColumnNames {
+			if !opts.columns[name] {
+				continue
+			}
+			switch name {
+			case "languages":
+				row = append(row, strings.Join(item.Languages, " "))
+			case "subtitles":
+				row = append(row, strconv.Itoa(*item.Subtitles))
+			case "status":
+				row = append(row, item.Status)
+			case "monitored":
+				row = append(row, strconv.FormatBool(*item.Monitored))
+			case "imdb":
+				row = append(row, item.ImdbId)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return header, rows
+}
+
+// writeListTable prints the items as a table whose columns fit the longest
+// cells, so titles are never cut.
+func writeListTable(items []listedItem, opts listOptions, idName string) {
+	header, rows := listColumnsOf(items, opts, idName)
+	rule := make([]string, len(header))
+	for i, name := range header {
+		header[i] = listHeadings[name]
+		rule[i] = strings.Repeat("-", len(header[i]))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	fmt.Fprintln(w, strings.Join(rule, "\t"))
+	for _, row := range rows {
+		for i, cell := range row {
+			if cell == "" {
+				row[i] = "-"
+			}
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
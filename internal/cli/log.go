@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/logging"
+)
+
+// Command line overrides of the Log section, empty when not given
+var logLevel string
+var logFormat string
+var logFile string
+
+// bootstrapLogging sets up a console logger from the flags alone, for the
+// messages logged while the config file is loaded.
+func bootstrapLogging() {
+	opts := logging.Options{Level: "info", Format: "text"}
+	if _, err := logging.ParseLevel(logLevel); err == nil && logLevel != "" {
+		opts.Level = logLevel
+	}
+	if logFormat == "json" {
+		opts.Format = "json"
+	}
+	logging.Setup(opts)
+}
+
+// setupLogging sets up the logger from the Log section of the config, with
+// the command line flags taking precedence.
+func setupLogging(cfg config.Config) error {
+	opts := logging.Options{
+		Level:      cfg.Log.Level,
+		Format:     cfg.Log.Format,
+		File:       cfg.Log.File,
+		MaxSize:    cfg.Log.MaxSize,
+		MaxBackups: cfg.Log.MaxBackups,
+		MaxAge:     cfg.Log.MaxAge,
+	}
+	if logLevel != "" {
+		opts.Level = logLevel
+	}
+	if logFormat != "" {
+		opts.Format = logFormat
+	}
+	if logFile != "" {
+		opts.File = logFile
+	}
+	return logging.Setup(opts)
+}
+
+// logger returns the logger for messages about the run, which carry its ID
+// and job.
+func (r *syncRun) logger() *slog.Logger {
+	log := slog.With("run_id", r.ID, "trigger", r.Trigger)
+	if r.Job != "" {
+		log = log.With("job", r.Job)
+	}
+	return log
+}
+
+// logEvent logs the progress of a run. Only the start and end of runs and
+// parts, and failures, are logged at info level and above; the rest is for
+// debugging.
+func (r *syncRun) logEvent(ev syncEvent) {
+	log := r.logger()
+	subtitle := func() []any {
+		return []any{"part", ev.Part, "kind", ev.Kind, "media_id", ev.Id, "media", ev.Media,
+			"title", ev.Title, "language", ev.Language, "path", ev.Path}
+	}
+
+	switch ev.Type {
+	case eventRunStarted:
+		log.Info("Run started")
+	case eventPartStarted:
+		log.Debug("Part started", "part", ev.Part)
+	case eventItem:
+		log.Debug("Processing", "part", ev.Part, "kind", ev.Kind, "media_id", ev.Id, "title", ev.Title,
+			"position", ev.Position, "total", ev.Total)
+	case eventSyncing:
+		log.Debug("Syncing subtitle", subtitle()...)
+	case eventRetry:
+		log.Debug("Retrying subtitle", append(subtitle(), "message", ev.Message)...)
+	case eventOutcome:
+		if ev.Outcome == outcomeFailed.String() {
+			log.Warn("Subtitle sync failed", append(subtitle(), "message", ev.Message)...)
+		} else {
+			log.Debug("Subtitle synced", append(subtitle(), "outcome", ev.Outcome, "message", ev.Message)...)
+		}
+	case eventSkipped:
+		log.Debug("Subtitle skipped", append(subtitle(), "reason", ev.Message)...)
+	case eventPartFinished:
+		attrs := []any{"part", ev.Part}
+		if ev.Summary != nil {
+			attrs = append(attrs, summaryAttrs(*ev.Summary)...)
+		}
+		r.mu.Lock()
+		err := r.Parts[len(r.Parts)-1].Err
+		r.mu.Unlock()
+		switch {
+		case errors.Is(err, context.Canceled):
+			log.Info("Part cancelled", attrs...)
+		case err != nil:
+			log.Error("Part failed", append(attrs, "err", err)...)
+		default:
+			log.Info("Part finished", attrs...)
+		}
+	case eventRunFinished:
+		r.mu.Lock()
+		duration := r.Finished.Sub(r.Started).Round(time.Second)
+		r.mu.Unlock()
+		attrs := []any{"status", r.status(), "duration", duration}
+		if ev.Summary != nil {
+			attrs = append(attrs, summaryAttrs(*ev.Summary)...)
+		}
+		log.Info("Run finished", attrs...)
+	}
+}
+
+func summaryAttrs(s syncSummary) []any {
+	return []any{"synced", s.Success, "already_synced", s.AlreadySynced, "skipped", s.Skipped, "failed", s.Failed}
+}
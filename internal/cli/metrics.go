@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/client"
+	"github.com/regix1/bazarr-sync/internal/metrics"
+)
+
+// serveMetrics starts collecting metrics and serves them on /metrics of the
+// control API.
+func (s *scheduler) serveMetrics() {
+	client.RequestObserver = func(req *http.Request, status int, duration time.Duration) {
+		metrics.RecordRequest(req.Method, req.URL.Path, status, duration)
+	}
+	addRunObserver(func(ev syncEvent) {
+		switch ev.Type {
+		case eventOutcome:
+			metrics.RecordSubtitle(ev.Outcome, ev.Kind, ev.Language)
+		case eventSkipped:
+			metrics.RecordSubtitle("skipped", ev.Kind, ev.Language)
+		}
+	})
+	metrics.WatchQueue(func() int {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.queue)
+	})
+	metrics.WatchCache(func(kind string) int {
+		cacheMu.RLock()
+		defer cacheMu.RUnlock()
+		if kind == "movies" {
+			return len(movies_cache)
+		}
+		return len(shows_cache)
+	})
+
+	s.srv.Handle("GET /metrics", metrics.Handler())
+}
@@ -2,7 +2,7 @@ package cli
 
 import (
 	"fmt"
-	"os"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -21,7 +21,8 @@ var moviesCmd = &cobra.Command{
 	Short:   "Sync subtitles to the audio track of movies",
 	Example: `  bazarr-sync sync movies
   bazarr-sync sync movies --list
-  bazarr-sync sync movies --radarr-id 123,456`,
+  bazarr-sync sync movies --radarr-id 123,456
+  bazarr-sync sync movies --since 14d`,
 	Long: `By default, Bazarr will try to sync the sub to the audio track:0 of the media. 
 This can fail due to many reasons mainly due to failure of bazarr to extract audio info. This is unfortunately out of my hands.
 The script by default will try to not use the golden section search method and will try to fix framerate issues. This can be changed using the flags.`,
@@ -29,33 +30,36 @@ The script by default will try to not use the golden section search method and w
 		cfg := config.GetConfig()
 
 		// Override config with command line flags
-		if cmd.Flags().Changed("golden-section") {
-			cfg.SyncOptions.GoldenSection = gss
-		}
-		if cmd.Flags().Changed("no-framerate-fix") {
-			cfg.SyncOptions.NoFramerateFix = no_framerate_fix
-		}
-		if cmd.Flags().Changed("use-cache") {
-			cfg.Cache.Enabled = use_cache
-		}
+		applyFlagOverrides(cmd, &cfg)
 		if cmd.Flags().Changed("verbose") {
 			verbose = true
 		}
 
+		checkConfig(cfg)
+
 		if cfg.Cache.Enabled {
 			Load_cache(cfg)
 		}
 
-		bazarr.HealthCheck(cfg)
+		if err := bazarr.HealthCheck(cfg); err != nil {
+			exit(errorExitCode(err))
+		}
 
 		if to_list {
 			list_movies(cfg)
 			return
 		}
 
-		runWithSignalHandler(func(c chan int) {
-			sync_movies(cfg, c)
-		})
+		filter, err := newDownloadFilter(cfg, true)
+		if err != nil {
+			slog.Error("Invalid filter", "err", err)
+			exit(errorExitCode(err))
+		}
+
+		sel := selection{Ids: radarrid, ContinueFrom: moviesContinueFrom, Filter: filter}
+		run := newRun("manual", "")
+		runWithSignalHandler(run, "movie", []syncPart{moviesPart(cfg, sel)})
+		exitForRun(run)
 	},
 }
 
@@ -64,13 +68,14 @@ func init() {
 	moviesCmd.Flags().IntSliceVar(&radarrid, "radarr-id", []int{}, "Specify a list of radarr Ids to sync. Use --list to view your movies with respective radarr id.")
 	moviesCmd.Flags().IntVar(&moviesContinueFrom, "continue-from", -1, "Continue with the given Radarr movie ID.")
 	moviesCmd.Flags().BoolVar(&verbose, "verbose", false, "Show detailed error messages")
+	moviesCmd.Flags().StringVar(&syncSince, "since", "", "Only sync subtitles Bazarr downloaded since a duration ago (14d, 36h) or a date (2026-09-01).")
+	moviesCmd.Flags().StringVar(&syncUntil, "until", "", "Only sync subtitles Bazarr downloaded until a duration ago or a date.")
 }
 
-func sync_movies(cfg config.Config, c chan int) {
+func sync_movies(run *syncRun, cfg config.Config, sel selection) error {
 	movies, err := bazarr.QueryMovies(cfg)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Query Error: Could not query movies")
-		return
+		return fmt.Errorf("could not query movies: %w", err)
 	}
 
 	totalMovies := len(movies.Data)
@@ -78,181 +83,51 @@ func sync_movies(cfg config.Config, c chan int) {
 	fmt.Println("Starting sync process...")
 	fmt.Println(strings.Repeat("-", 60))
 
-	skipForward := moviesContinueFrom != -1
-	successCount := 0
-	skipCount := 0
-	failCount := 0
-	alreadySyncedCount := 0
-
-	// Spinner characters
-	spinners := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	skipForward := sel.ContinueFrom != -1
 
-movies:
 	for i, movie := range movies.Data {
-		if len(radarrid) > 0 {
-			found := false
-			for _, id := range radarrid {
-				if id == movie.RadarrId {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue movies
-			}
+		if !sel.includes(movie.RadarrId) || !sel.Filter.allowsId(movie.RadarrId) {
+			continue
 		}
 
 		if skipForward {
-			if movie.RadarrId == moviesContinueFrom {
+			if movie.RadarrId == sel.ContinueFrom {
 				skipForward = false
 			} else {
-				fmt.Printf("[%d/%d] SKIPPING: %s (continue mode)\n", i+1, totalMovies, movie.Title)
-				skipCount++
+				run.skip(subtitleRef{Kind: "movie", Id: movie.RadarrId, Title: movie.Title}, "continue mode")
 				continue
 			}
 		}
 
-		c <- movie.RadarrId
+		run.item("movie", movie.RadarrId, movie.Title, i+1, totalMovies)
 
-		if len(movie.Subtitles) == 0 {
-			fmt.Printf("[%d/%d] NO SUBS: %s\n", i+1, totalMovies, movie.Title)
-			continue
-		}
+		for _, subtitle := range movie.Subtitles {
+			if !sel.Filter.allowsPath(subtitle.Path) {
+				continue
+			}
+			if err := run.stopped(); err != nil {
+				return err
+			}
 
-		fmt.Printf("[%d/%d] PROCESSING: %s (%d subtitles)\n", i+1, totalMovies, movie.Title, len(movie.Subtitles))
+			ref := subtitleRef{Kind: "movie", Id: movie.RadarrId, Title: movie.Title, Media: movie.Title, Language: subtitle.Code2, Path: subtitle.Path}
 
-		for _, subtitle := range movie.Subtitles {
 			if subtitle.Path == "" || subtitle.File_size == 0 {
-				fmt.Printf("  └─ SKIP [%s]: Embedded or missing subtitle\n", subtitle.Code2)
-				skipCount++
+				run.skip(ref, "embedded or missing")
 				continue
 			}
 
 			if cfg.Cache.Enabled {
 				_, exists := movies_cache[subtitle.Path]
 				if exists {
-					fmt.Printf("  └─ CACHED [%s]: Already synced\n", subtitle.Code2)
-					skipCount++
+					run.skip(ref, "cached")
 					continue
 				}
 			}
 
-			params := bazarr.GetSyncParams("movie", movie.RadarrId, subtitle)
-			if cfg.SyncOptions.GoldenSection {
-				params.Gss = "True"
-			}
-			if cfg.SyncOptions.NoFramerateFix {
-				params.No_framerate_fix = "True"
-			}
-
-			// Start sync with spinner
-			fmt.Printf("  └─ SYNCING [%s]: ", subtitle.Code2)
-
-			// Start sync in background
-			syncDone := make(chan struct {
-				success bool
-				message string
-			})
-
-			go func() {
-				ok, msg := bazarr.Sync(cfg, params)
-				syncDone <- struct {
-					success bool
-					message string
-				}{ok, msg}
-			}()
-
-			// Show spinner while waiting
-			spinnerIndex := 0
-			ticker := time.NewTicker(100 * time.Millisecond)
-			defer ticker.Stop()
-
-			var result struct {
-				success bool
-				message string
-			}
-
-		spinnerLoop:
-			for {
-				select {
-				case result = <-syncDone:
-					break spinnerLoop
-				case <-ticker.C:
-					fmt.Printf("\r  └─ SYNCING [%s]: %s ", subtitle.Code2, spinners[spinnerIndex])
-					spinnerIndex = (spinnerIndex + 1) % len(spinners)
-				}
-			}
-
-			// Clear spinner and show result
-			fmt.Printf("\r  └─ SYNCING [%s]: ", subtitle.Code2)
-
-			if result.success {
-				fmt.Printf("✓ Success                    \n")
+			outcome := syncSubtitle(run, cfg, ref)
+			if outcome != outcomeFailed {
+				// Already in sync is cached too, so we don't try again
 				Write_movies_cache(cfg, subtitle.Path)
-				successCount++
-			} else {
-				// Check if it's already synced
-				if strings.Contains(strings.ToLower(result.message), "already") ||
-					strings.Contains(strings.ToLower(result.message), "sync") ||
-					strings.Contains(result.message, "304") ||
-					strings.Contains(result.message, "409") {
-					fmt.Printf("✓ Already in sync            \n")
-					Write_movies_cache(cfg, subtitle.Path) // Cache it so we don't try again
-					alreadySyncedCount++
-				} else {
-					// Retry once for real failures
-					if verbose {
-						fmt.Printf("✗ Failed (%s), retrying...\n  └─ RETRYING [%s]: ", result.message, subtitle.Code2)
-					} else {
-						fmt.Printf("✗ Failed, retrying...        \n  └─ RETRYING [%s]: ", subtitle.Code2)
-					}
-
-					// Retry with spinner
-					go func() {
-						time.Sleep(2 * time.Second)
-						ok, msg := bazarr.Sync(cfg, params)
-						syncDone <- struct {
-							success bool
-							message string
-						}{ok, msg}
-					}()
-
-					// Show spinner for retry
-					spinnerIndex = 0
-					ticker = time.NewTicker(100 * time.Millisecond)
-				retrySpinner:
-					for {
-						select {
-						case result = <-syncDone:
-							ticker.Stop()
-							break retrySpinner
-						case <-ticker.C:
-							fmt.Printf("\r  └─ RETRYING [%s]: %s ", subtitle.Code2, spinners[spinnerIndex])
-							spinnerIndex = (spinnerIndex + 1) % len(spinners)
-						}
-					}
-
-					// Clear spinner and show retry result
-					fmt.Printf("\r  └─ RETRYING [%s]: ", subtitle.Code2)
-
-					if result.success {
-						fmt.Printf("✓ Success                    \n")
-						Write_movies_cache(cfg, subtitle.Path)
-						successCount++
-					} else if strings.Contains(strings.ToLower(result.message), "already") ||
-						strings.Contains(strings.ToLower(result.message), "sync") {
-						fmt.Printf("✓ Already in sync            \n")
-						Write_movies_cache(cfg, subtitle.Path)
-						alreadySyncedCount++
-					} else {
-						if verbose {
-							fmt.Printf("✗ Failed: %s\n", result.message)
-						} else {
-							fmt.Printf("✗ Failed                     \n")
-						}
-						failCount++
-					}
-				}
 			}
 
 			// Add delay between syncs to avoid overwhelming Bazarr
@@ -260,33 +135,23 @@ movies:
 		}
 	}
 
-	fmt.Println(strings.Repeat("-", 60))
-	fmt.Printf("Sync completed:\n")
-	fmt.Printf("  ✅ %d newly synced\n", successCount)
-	fmt.Printf("  ✓  %d already in sync\n", alreadySyncedCount)
-	fmt.Printf("  ⏭️  %d skipped (cached/embedded)\n", skipCount)
-	fmt.Printf("  ❌ %d failed\n", failCount)
-
-	if failCount > 0 && !verbose {
-		fmt.Println("\n💡 Tip: Run with --verbose to see detailed error messages")
-	}
-
-	close(c)
+	return nil
 }
 
 func list_movies(cfg config.Config) {
 	movies, err := bazarr.QueryMovies(cfg)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Query Error: Could not query movies")
-		return
+		slog.Error("Could not query movies", "err", err)
+		exit(errorExitCode(err))
 	}
 
-	fmt.Printf("%-60s %s\n", "Title", "RadarrId")
-	fmt.Println(strings.Repeat("-", 70))
-
+	states := loadSyncStates(cfg)
+	var items []listedItem
 	for _, movie := range movies.Data {
-		fmt.Printf("%-60s %d\n", movie.Title, movie.RadarrId)
+		item := listedItem{Title: movie.Title, RadarrId: movie.RadarrId}
+		item.addSubtitles(movie.Subtitles, states)
+		item.setColumns(listOpts, movie.Monitored, movie.ImdbId)
+		items = append(items, item)
 	}
-
-	fmt.Printf("\nTotal: %d movies\n", len(movies.Data))
+	printList(filterList(items, listOpts), listOpts, "radarr_id", "movies")
 }
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/mqtt"
+)
+
+// startMqtt connects to the MQTT broker and keeps the published state in
+// step with the runs.
+func (s *scheduler) startMqtt(cfg config.MqttConfig) {
+	s.mqtt = mqtt.Connect(cfg, s.mqttCommand, func(connected bool, err error) {
+		if connected {
+			slog.Info("Connected to MQTT broker", "broker", cfg.Broker)
+			return
+		}
+		slog.Warn("Lost connection to MQTT broker. Reconnecting", "broker", cfg.Broker, "err", err)
+	})
+
+	addRunObserver(func(ev syncEvent) {
+		switch ev.Type {
+		case eventRunStarted:
+			var job string
+			s.mu.Lock()
+			if s.current != nil && s.current.run.ID == ev.RunId {
+				job = s.current.run.Job
+			}
+			s.mu.Unlock()
+			s.mqtt.Update(func(state *mqtt.State) {
+				state.State = "running"
+				state.RunId, state.Trigger, state.Job = ev.RunId, ev.Message, job
+				state.Current, state.Progress = "", 0
+			})
+		case eventPartStarted:
+			s.mqtt.Update(func(state *mqtt.State) {
+				state.Current, state.Progress = "", 0
+			})
+		case eventItem:
+			progress := 0
+			if ev.Total > 0 {
+				progress = (ev.Position - 1) * 100 / ev.Total
+			}
+			s.mqtt.Update(func(state *mqtt.State) {
+				state.Current, state.Progress = ev.Title, progress
+			})
+		case eventOutcome:
+			failures := len(s.Failures())
+			s.mqtt.Update(func(state *mqtt.State) {
+				state.Failures = failures
+			})
+		}
+	})
+
+	onRunFinished(func(run *syncRun) {
+		snap := run.snapshot()
+		failures := len(s.Failures())
+		s.mqtt.Update(func(state *mqtt.State) {
+			*state = mqtt.State{
+				State: "idle",
+				LastRun: mqtt.LastRun{
+					Id:            run.ID,
+					Trigger:       run.Trigger,
+					Job:           run.Job,
+					Status:        run.status(),
+					Finished:      snap.Finished.Format(time.RFC3339),
+					Synced:        snap.Summary.Success,
+					AlreadySynced: snap.Summary.AlreadySynced,
+					Skipped:       snap.Summary.Skipped,
+					Failed:        snap.Summary.Failed,
+				},
+				Failures: failures,
+			}
+		})
+	})
+}
+
+// mqttCommand handles a message on the command topic.
+func (s *scheduler) mqttCommand(payload string) {
+	cmd, ok := mqtt.ParseCommand(payload)
+	if !ok {
+		slog.Warn("Ignoring unknown MQTT command", "command", strings.TrimSpace(payload))
+		return
+	}
+
+	switch cmd.Name {
+	case "cancel":
+		runId, err := s.Cancel()
+		if err != nil {
+			slog.Info("MQTT cancel command received, but no run is in progress")
+			return
+		}
+		slog.Info("Cancelling run on MQTT request", "run_id", runId)
+	case "run":
+		job := cmd.Job
+		if job == "" {
+			job = "sync"
+		}
+		runId, err := s.runJob(job, "mqtt")
+		if err != nil {
+			slog.Warn("Could not run MQTT command", "job", job, "err", err)
+			return
+		}
+		slog.Info("Queued job on MQTT request", "job", job, "run_id", runId)
+	}
+}
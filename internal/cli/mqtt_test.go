@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/mqtt/mqtttest"
+)
+
+// waitUntil polls done until it holds, failing the test after a while.
+func waitUntil(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMqttCommandsReachScheduler(t *testing.T) {
+	broker, err := mqtttest.NewBroker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer broker.Close()
+
+	s := &scheduler{
+		jobs: []scheduledJob{{Name: "sync", Spec: "@daily"}, {Name: "full-sync", Spec: "@weekly", Full: true}},
+		wake: make(chan struct{}, 1),
+	}
+	s.startMqtt(config.MqttConfig{Broker: broker.URL, ClientId: "bazarr-sync-test", TopicPrefix: "bazarr-sync"})
+	defer s.mqtt.Close()
+	waitUntil(t, "the command subscription", func() bool { return broker.Subscribed("bazarr-sync/command") })
+
+	queued := func() []*queuedRun {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return append([]*queuedRun(nil), s.queue...)
+	}
+	for i, command := range []struct{ payload, job string }{{"run", "sync"}, {"RUN full-sync", "full-sync"}} {
+		if err := broker.Publish("bazarr-sync/command", command.payload); err != nil {
+			t.Fatal(err)
+		}
+		waitUntil(t, "the "+command.job+" run", func() bool { return len(queued()) == i+1 })
+		q := queued()[i]
+		if q.job == nil || q.job.Name != command.job || q.run.Trigger != "mqtt" {
+			t.Errorf("%q queued %+v, want the %s job triggered by mqtt", command.payload, q.run, command.job)
+		}
+	}
+
+	// Unknown jobs and commands are ignored
+	broker.Publish("bazarr-sync/command", "run nightly")
+	broker.Publish("bazarr-sync/command", "pause")
+
+	current := &queuedRun{run: newRun("schedule", "sync")}
+	s.mu.Lock()
+	s.current = current
+	s.mu.Unlock()
+	if err := broker.Publish("bazarr-sync/command", "cancel"); err != nil {
+		t.Fatal(err)
+	}
+	waitUntil(t, "the run to be cancelled", current.run.Cancelled)
+	if n := len(queued()); n != 2 {
+		t.Errorf("%d runs queued, want 2", n)
+	}
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/bazarr"
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/server"
+)
+
+// How far back Bazarr's history is searched for the subtitle of a
+// notification
+const notificationLookback = 24 * time.Hour
+
+// Bazarr writes the history entry right before it notifies, but a busy
+// instance may lag; the lookup is tried this many times before giving up.
+const notificationAttempts = 3
+const notificationRetryDelay = 10 * time.Second
+
+// Notified implements server.Controller.
+func (s *scheduler) Notified(n server.SubtitleNotification) error {
+	s.mu.Lock()
+	cfg := s.cfg
+	s.mu.Unlock()
+
+	slog.Info("Bazarr notified of a subtitle", "action", n.Action, "language", n.Language, "media", describeNotification(n))
+	go s.resolveNotification(cfg, n)
+	return nil
+}
+
+// resolveNotification looks the notified subtitle up in Bazarr's history,
+// which has the IDs and path the notification lacks, and queues its sync.
+func (s *scheduler) resolveNotification(cfg config.Config, n server.SubtitleNotification) {
+	for attempt := 1; ; attempt++ {
+		item, found, err := findNotifiedSubtitle(cfg, n)
+		if err != nil {
+			slog.Warn("Could not search Bazarr's history", "media", describeNotification(n), "err", err)
+		}
+		if found {
+			s.enqueue(&queuedRun{run: newRun("notification", ""), items: []historyItem{item}})
+			return
+		}
+		if attempt == notificationAttempts {
+			slog.Warn("Notified subtitle not found in Bazarr's history. Not syncing it.", "language", n.Language, "media", describeNotification(n))
+			return
+		}
+		time.Sleep(notificationRetryDelay)
+	}
+}
+
+// findNotifiedSubtitle returns the newest history entry matching the
+// notification.
+func findNotifiedSubtitle(cfg config.Config, n server.SubtitleNotification) (historyItem, bool, error) {
+	since := time.Now().Add(-notificationLookback)
+
+	if n.Kind == "movie" {
+		entries, err := bazarr.QueryMoviesHistory(cfg, since)
+		if err != nil {
+			return historyItem{}, false, err
+		}
+		for _, entry := range entries {
+			if !entry.IsNewSubtitle() || !strings.EqualFold(entry.Title, n.Title) || !languageMatches(entry.Language, n) {
+				continue
+			}
+			return historyItem{
+				kind:       "movie",
+				id:         entry.RadarrId,
+				title:      entry.Title,
+				media:      entry.Title,
+				subtitle:   entry.Subtitle(),
+				upgraded:   entry.Action == bazarr.HistoryUpgraded,
+				downloaded: entry.Time(),
+			}, true, nil
+		}
+		return historyItem{}, false, nil
+	}
+
+	entries, err := bazarr.QueryEpisodesHistory(cfg, since)
+	if err != nil {
+		return historyItem{}, false, err
+	}
+	for _, entry := range entries {
+		if !entry.IsNewSubtitle() || !strings.EqualFold(entry.SeriesTitle, n.Title) || !languageMatches(entry.Language, n) {
+			continue
+		}
+		// Bazarr numbers episodes as "1x02"
+		var season, episode int
+		if _, err := fmt.Sscanf(entry.EpisodeNumber, "%dx%d", &season, &episode); err != nil ||
+			season != n.Season || episode != n.Episode {
+			continue
+		}
+		return historyItem{
+			kind:       "episode",
+			id:         entry.SonarrEpisodeId,
+			seriesId:   entry.SonarrSeriesId,
+			title:      fmt.Sprintf("%s %s - %s", entry.SeriesTitle, entry.EpisodeNumber, entry.EpisodeTitle),
+			media:      entry.SeriesTitle,
+			subtitle:   entry.Subtitle(),
+			upgraded:   entry.Action == bazarr.HistoryUpgraded,
+			downloaded: entry.Time(),
+		}, true, nil
+	}
+	return historyItem{}, false, nil
+}
+
+func languageMatches(language bazarr.HistoryLanguage, n server.SubtitleNotification) bool {
+	return strings.EqualFold(language.Name, n.Language) && language.Forced == n.Forced && language.Hi == n.HI
+}
+
+func describeNotification(n server.SubtitleNotification) string {
+	if n.Kind == "episode" {
+		return fmt.Sprintf("%s S%02dE%02d", n.Title, n.Season, n.Episode)
+	}
+	return n.Title
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/history"
+	"github.com/regix1/bazarr-sync/internal/notify"
+)
+
+// How long a notification target gets to accept a message
+const notifyTimeout = 10 * time.Second
+
+// nextScheduledRun returns when the scheduler runs next. It is set while
+// the scheduler is running and included in reports.
+var nextScheduledRun func() time.Time
+
+func init() {
+	onRunFinished(notifyRun)
+}
+
+// notifyRun sends the result of a finished run to the configured targets.
+// The configuration is read when the run finishes, so the daemon picks up
+// changed targets without a restart. A target that cannot be reached is
+// only logged.
+func notifyRun(run *syncRun) {
+	targets := config.GetConfig().Notifications
+	if len(targets) == 0 {
+		return
+	}
+
+	report := runReport(run)
+	for _, target := range targets {
+		event, ok := notify.Event(target, report)
+		if !ok {
+			continue
+		}
+		report.Event = event
+
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		err := notify.Send(ctx, target, report)
+		cancel()
+		if err != nil {
+			run.logger().Warn("Could not send notification", "target", notify.Name(target), "err", err)
+		}
+	}
+}
+
+// runReport converts a finished run to the data passed to notification
+// templates.
+func runReport(run *syncRun) notify.Report {
+	snap := run.snapshot()
+	report := notify.Report{
+		RunId:    run.ID,
+		Trigger:  run.Trigger,
+		Job:      run.Job,
+		Status:   run.status(),
+		Started:  snap.Started,
+		Finished: snap.Finished,
+		Duration: snap.Finished.Sub(snap.Started).Round(time.Second),
+		Summary:  notify.Summary(snap.Summary),
+		Parts:    []notify.Part{},
+		Synced:   []notify.Subtitle{},
+		Failures: []notify.Failure{},
+	}
+	for _, part := range snap.Parts {
+		p := notify.Part{Name: part.Name, Summary: notify.Summary(part.Summary)}
+		if part.Err != nil {
+			p.Error = part.Err.Error()
+		}
+		report.Parts = append(report.Parts, p)
+	}
+	for _, ref := range snap.Synced {
+		report.Synced = append(report.Synced, reportSubtitle(ref))
+	}
+	for _, failure := range snap.Failures {
+		report.Failures = append(report.Failures, notify.Failure{
+			Subtitle: reportSubtitle(failure.subtitleRef),
+			Message:  failure.Message,
+		})
+	}
+	cfg := config.GetConfig()
+	report.Quarantined = newlyQuarantined(cfg, run, report.Failures)
+	report.QuarantineAfter = cfg.History.QuarantineAfter
+	if nextScheduledRun != nil {
+		if next := nextScheduledRun(); !next.IsZero() {
+			report.NextRun = &next
+		}
+	}
+	return report
+}
+
+// newlyQuarantined returns the failures of a run that make their subtitle
+// quarantined: the ones that had failed in the runs in a row just before, one
+// short of History.QuarantineAfter.
+func newlyQuarantined(cfg config.Config, run *syncRun, failures []notify.Failure) []notify.Failure {
+	quarantined := []notify.Failure{}
+	if cfg.History.Dir == "" || cfg.History.QuarantineAfter < 1 || len(failures) == 0 {
+		return quarantined
+	}
+	runs, err := history.Open(cfg.History.Dir).Runs(0)
+	if err != nil {
+		run.logger().Warn("Could not read the run history", "dir", cfg.History.Dir, "err", err)
+	}
+	// The run itself is recorded after notifications are sent
+	before := make(map[string]int)
+	for _, f := range history.Failures(runs) {
+		if f.Failed {
+			before[f.Path] = f.Consecutive
+		}
+	}
+	for _, failure := range failures {
+		if before[failure.Path]+1 == cfg.History.QuarantineAfter {
+			quarantined = append(quarantined, failure)
+		}
+	}
+	return quarantined
+}
+
+func reportSubtitle(ref subtitleRef) notify.Subtitle {
+	return notify.Subtitle{
+		Kind:     ref.Kind,
+		Id:       ref.Id,
+		Title:    ref.Title,
+		Media:    ref.Media,
+		Language: ref.Language,
+		Path:     ref.Path,
+	}
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/history"
+	"github.com/regix1/bazarr-sync/internal/notify"
+)
+
+// A subtitle is announced as quarantined by the run that makes it fail
+// QuarantineAfter times in a row, and not again by the runs after that.
+func TestNewlyQuarantined(t *testing.T) {
+	cfg := config.Config{History: config.HistoryConfig{Dir: t.TempDir(), QuarantineAfter: 3}}
+	store := history.Open(cfg.History.Dir)
+	save := func(i int, outcomes map[string]string) {
+		run := history.Run{Entry: history.Entry{Id: fmt.Sprintf("20260101-00000%d", i)}}
+		for path, outcome := range outcomes {
+			run.Subtitles = append(run.Subtitles, history.Subtitle{Kind: "movie", Path: path, Outcome: outcome})
+		}
+		if err := store.Save(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a.srt failed twice, b.srt failed twice but synced in between,
+	// c.srt has been failing for three runs already
+	save(1, map[string]string{"a.srt": "success", "b.srt": "failed", "c.srt": "failed"})
+	save(2, map[string]string{"a.srt": "failed", "b.srt": "success", "c.srt": "failed"})
+	save(3, map[string]string{"a.srt": "failed", "b.srt": "failed", "c.srt": "failed"})
+
+	var failures []notify.Failure
+	for _, path := range []string{"a.srt", "b.srt", "c.srt", "d.srt"} {
+		failures = append(failures, notify.Failure{Subtitle: notify.Subtitle{Path: path}})
+	}
+	quarantined := newlyQuarantined(cfg, newRun("manual", ""), failures)
+	if len(quarantined) != 1 || quarantined[0].Path != "a.srt" {
+		t.Errorf("quarantined = %+v, want a.srt", quarantined)
+	}
+
+	cfg.History.QuarantineAfter = 0
+	if quarantined := newlyQuarantined(cfg, newRun("manual", ""), failures); len(quarantined) != 0 {
+		t.Errorf("quarantined with QuarantineAfter 0 = %+v, want none", quarantined)
+	}
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Formats of --output. The JSON formats are described in docs/output.md;
+// fields may be added, but existing ones keep their name and meaning.
+const (
+	outputText   = "text"
+	outputJSON   = "json"   // one summary object per run
+	outputNDJSON = "ndjson" // one object per run event
+)
+
+var outputFormat string
+
+// structuredOut writes JSON output to the real stdout. It is nil in text
+// mode.
+var structuredOut *json.Encoder
+var structuredMu sync.Mutex
+
+// setupOutput prepares the output format chosen with --output. In the JSON
+// formats, everything meant for people is written to stderr instead, so
+// stdout only carries JSON.
+func setupOutput() error {
+	switch outputFormat {
+	case outputText:
+		return nil
+	case outputJSON:
+		onRunFinished(func(run *syncRun) {
+			writeStructured(newRunOutput(run))
+		})
+	case outputNDJSON:
+		addRunObserver(func(ev syncEvent) {
+			writeStructured(ev)
+		})
+	default:
+		return fmt.Errorf("--output must be text, json or ndjson, got %q", outputFormat)
+	}
+
+	structuredOut = json.NewEncoder(divertHumanOutput())
+	structuredOut.SetEscapeHTML(false)
+	return nil
+}
+
+// divertHumanOutput sends everything meant for people to stderr and returns
+// the real stdout, for machine-readable output.
+func divertHumanOutput() *os.File {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	pterm.SetDefaultOutput(os.Stderr)
+	return stdout
+}
+
+// structuredOutput reports whether a JSON format was chosen.
+func structuredOutput() bool {
+	return structuredOut != nil
+}
+
+// writeStructured writes one value as a line of JSON.
+func writeStructured(v any) {
+	structuredMu.Lock()
+	defer structuredMu.Unlock()
+	if err := structuredOut.Encode(v); err != nil {
+		slog.Error("Could not write output", "err", err)
+	}
+}
+
+// writeList writes listed items: as one array with --output json, or one
+// item per line with --output ndjson.
+func writeList[T any](items []T) {
+	if outputFormat == outputJSON {
+		if items == nil {
+			items = []T{}
+		}
+		writeStructured(items)
+		return
+	}
+	for _, item := range items {
+		writeStructured(item)
+	}
+}
+
+// runOutput is the summary of a run printed with --output json.
+type runOutput struct {
+	RunId    string          `json:"run_id"`
+	Trigger  string          `json:"trigger"`
+	Job      string          `json:"job,omitempty"`
+	RetryOf  string          `json:"retry_of,omitempty"`
+	Status   string          `json:"status"` // completed, failed or cancelled
+	Result   string          `json:"result"`
+	ExitCode int             `json:"exit_code"`
+	Started  time.Time       `json:"started"`
+	Finished time.Time       `json:"finished"`
+	Duration float64         `json:"duration_seconds"`
+	Summary  syncSummary     `json:"summary"`
+	Parts    []partOutput    `json:"parts"`
+	Failures []failureOutput `json:"failures"`
+}
+
+type partOutput struct {
+	Name    string      `json:"name"`
+	Summary syncSummary `json:"summary"`
+	Error   string      `json:"error,omitempty"`
+}
+
+type failureOutput struct {
+	Kind     string `json:"kind"`
+	Id       int    `json:"id"`
+	Title    string `json:"title"`
+	Language string `json:"language"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+func newRunOutput(run *syncRun) runOutput {
+	snap := run.snapshot()
+	code := runExitCode(run)
+	out := runOutput{
+		RunId:    run.ID,
+		Trigger:  run.Trigger,
+		Job:      run.Job,
+		RetryOf:  run.RetryOf,
+		Status:   run.status(),
+		Result:   exitResults[code],
+		ExitCode: code,
+		Started:  snap.Started,
+		Finished: snap.Finished,
+		Duration: snap.Finished.Sub(snap.Started).Seconds(),
+		Summary:  snap.Summary,
+		Parts:    []partOutput{},
+		Failures: []failureOutput{},
+	}
+	for _, part := range snap.Parts {
+		p := partOutput{Name: part.Name, Summary: part.Summary}
+		if part.Err != nil {
+			p.Error = part.Err.Error()
+		}
+		out.Parts = append(out.Parts, p)
+	}
+	for _, failure := range snap.Failures {
+		out.Failures = append(out.Failures, failureOutput{
+			Kind:     failure.Kind,
+			Id:       failure.Id,
+			Title:    failure.Title,
+			Language: failure.Language,
+			Path:     failure.Path,
+			Message:  failure.Message,
+		})
+	}
+	return out
+}
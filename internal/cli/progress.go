@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"atomicgo.dev/cursor"
+	"github.com/pterm/pterm"
+	"golang.org/x/term"
+)
+
+// Modes of --progress. auto picks fancy when stdout is a terminal and plain
+// otherwise, so that logs collected by Docker or systemd don't fill up with
+// spinner frames.
+const (
+	progressAuto  = "auto"
+	progressFancy = "fancy" // live progress bar with counters and ETA
+	progressPlain = "plain" // one line per outcome
+	progressNone  = "none"  // only the summaries
+)
+
+var progressFlag string
+
+// progressMode is the mode in effect, never auto.
+var progressMode = progressPlain
+
+// Columns kept for the count, percentage, elapsed time and the bar itself
+// next to the title of the progress bar
+const progressBarColumns = 36
+
+// setupProgress resolves --progress. It must run after setupOutput, which
+// may point stdout elsewhere.
+func setupProgress() error {
+	switch progressFlag {
+	case progressAuto:
+		progressMode = progressPlain
+		if isTerminal(os.Stdout) {
+			progressMode = progressFancy
+		}
+	case progressFancy, progressPlain, progressNone:
+		progressMode = progressFlag
+	default:
+		return fmt.Errorf("--progress must be auto, fancy, plain or none, got %q", progressFlag)
+	}
+
+	switch progressMode {
+	case progressPlain:
+		addRunObserver((&plainProgress{}).observe)
+	case progressFancy:
+		// The bar hides the cursor while it is drawn; with --output json
+		// that must happen on stderr too
+		cursor.SetTarget(os.Stdout)
+		bar := &progressBar{}
+		addRunObserver(bar.observe)
+	}
+	return nil
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe, a file or
+// /dev/null.
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// progressf prints a per-item line of work other than syncing, such as the
+// shows status queries. Only the plain mode shows them.
+func progressf(format string, a ...any) {
+	if progressMode == progressPlain {
+		fmt.Printf(format, a...)
+	}
+}
+
+// plainProgress prints the lines of the plain mode: one per subtitle once
+// its outcome is known, so a retry adds no line of its own.
+type plainProgress struct {
+	mu       sync.Mutex
+	position int
+	total    int
+}
+
+func (p *plainProgress) observe(ev syncEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch ev.Type {
+	case eventPartStarted:
+		p.position, p.total = 0, 0
+	case eventItem:
+		p.position, p.total = ev.Position, ev.Total
+	case eventOutcome, eventSkipped:
+		fmt.Println(p.line(ev))
+	}
+}
+
+// line formats an outcome as "[3/155] Inception [en]: ✓ Success", with the
+// retries and, with --verbose, the error of a failure.
+func (p *plainProgress) line(ev syncEvent) string {
+	var line strings.Builder
+	if p.total > 0 {
+		fmt.Fprintf(&line, "[%d/%d] ", p.position, p.total)
+	}
+	line.WriteString(ev.Title)
+	if ev.Language != "" {
+		fmt.Fprintf(&line, " [%s]", ev.Language)
+	}
+	switch {
+	case ev.Type == eventSkipped:
+		fmt.Fprintf(&line, ": ⏭ Skipped (%s)", ev.Message)
+	case ev.Outcome == outcomeSuccess.String():
+		line.WriteString(": ✓ Success")
+	case ev.Outcome == outcomeAlreadySynced.String():
+		line.WriteString(": ✓ Already in sync")
+	case verbose:
+		fmt.Fprintf(&line, ": ✗ Failed: %s", ev.Message)
+	default:
+		line.WriteString(": ✗ Failed")
+	}
+	if ev.Retries > 0 {
+		fmt.Fprintf(&line, " retries=%d", ev.Retries)
+	}
+	return line.String()
+}
+
+// progressBar is the live display of the fancy mode: a bar over the items
+// of the part in progress, titled with the outcome counters, the ETA and
+// the current item.
+type progressBar struct {
+	mu      sync.Mutex
+	bar     *pterm.ProgressbarPrinter
+	counts  syncSummary
+	current string
+	// Position of the first item seen and when it started, for the ETA
+	first   int
+	started time.Time
+}
+
+func (p *progressBar) observe(ev syncEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch ev.Type {
+	case eventPartStarted:
+		p.counts = syncSummary{}
+		p.current = ""
+	case eventItem:
+		if ev.Total <= 0 {
+			return
+		}
+		if p.bar == nil {
+			p.first, p.started = ev.Position, time.Now()
+			p.bar, _ = pterm.DefaultProgressbar.
+				WithTotal(ev.Total).
+				WithWriter(os.Stdout).
+				WithMaxWidth(0).
+				WithRemoveWhenDone(true).
+				Start()
+		}
+		p.bar.Current = ev.Position - 1
+		p.current = ev.Title
+		p.refresh(ev.Position)
+	case eventOutcome:
+		p.counts.record(parseOutcome(ev.Outcome))
+		p.refresh(0)
+	case eventSkipped:
+		p.counts.Skipped++
+		p.refresh(0)
+	case eventPartFinished:
+		if p.bar != nil {
+			p.bar.Stop()
+			p.bar = nil
+		}
+	}
+}
+
+// refresh redraws the title. position is that of an item that just started,
+// or 0 to keep the ETA as it is.
+func (p *progressBar) refresh(position int) {
+	if p.bar == nil {
+		return
+	}
+	title := fmt.Sprintf("✅ %d ✓ %d ⏭ %d ❌ %d", p.counts.Success, p.counts.AlreadySynced, p.counts.Skipped, p.counts.Failed)
+	if eta, ok := p.eta(position); ok {
+		title += " · ETA " + eta.Round(time.Second).String()
+	}
+	// The bar is drawn on one line, so the item gets what is left of it
+	room := pterm.GetTerminalWidth() - progressBarColumns - len([]rune(title)) - 3
+	if p.current != "" && room >= 10 {
+		title += " · " + truncateTitle(p.current, room)
+	}
+	p.bar.UpdateTitle(title)
+}
+
+// eta estimates the time left from the pace of the items done since the
+// bar started.
+func (p *progressBar) eta(position int) (time.Duration, bool) {
+	if position == 0 {
+		position = p.bar.Current + 1
+	}
+	done := position - p.first
+	if done <= 0 {
+		return 0, false
+	}
+	perItem := time.Since(p.started) / time.Duration(done)
+	return perItem * time.Duration(p.bar.Total-position+1), true
+}
+
+func truncateTitle(title string, length int) string {
+	runes := []rune(title)
+	if len(runes) <= length {
+		return title
+	}
+	return string(runes[:length-1]) + "…"
+}
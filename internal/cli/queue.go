@@ -0,0 +1,280 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/metrics"
+	"github.com/regix1/bazarr-sync/internal/server"
+)
+
+// Number of finished runs kept for the control API
+const recentRunsKept = 50
+
+// queuedRun is a run waiting for its turn in the scheduler. Its parts are
+// only built when it starts, so it uses a configuration reloaded while it
+// was waiting.
+type queuedRun struct {
+	run    *syncRun
+	queued time.Time
+	// Set for scheduled jobs
+	job *scheduledJob
+	// Set for ad-hoc syncs
+	request *server.SyncRequest
+	// Set for a retry of a failed subtitle
+	retry *subtitleRef
+	// Set for subtitles reported by a webhook or notification
+	items []historyItem
+}
+
+func (s *scheduler) enqueue(q *queuedRun) string {
+	q.queued = time.Now()
+
+	s.mu.Lock()
+	s.queue = append(s.queue, q)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return q.run.ID
+}
+
+func (s *scheduler) enqueueJob(job scheduledJob, trigger string) string {
+	return s.enqueue(&queuedRun{run: newRun(trigger, job.Name), job: &job})
+}
+
+// work executes queued runs one at a time until the process exits.
+func (s *scheduler) work() {
+	for range s.wake {
+		for {
+			s.mu.Lock()
+			if len(s.queue) == 0 {
+				s.mu.Unlock()
+				break
+			}
+			q := s.queue[0]
+			s.queue = s.queue[1:]
+			s.current = q
+			s.mu.Unlock()
+
+			s.execute(q)
+			recordRunMetrics(q.run)
+
+			s.mu.Lock()
+			s.current = nil
+			s.recent = append([]*queuedRun{q}, s.recent...)
+			if len(s.recent) > recentRunsKept {
+				s.recent = s.recent[:recentRunsKept]
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *scheduler) execute(q *queuedRun) {
+	s.jobMu.Lock()
+	defer s.jobMu.Unlock()
+
+	s.mu.Lock()
+	cfg := s.cfg
+	s.mu.Unlock()
+
+	switch {
+	case q.job != nil:
+		runSyncJobs(q.run, cfg, q.job.Full)
+		s.mu.Lock()
+		s.printNextRun("Sync job completed.")
+		s.mu.Unlock()
+	case q.retry != nil:
+		fmt.Printf("\n%s Retrying failed subtitle %s\n", time.Now().Format("2006-01-02 15:04:05"), q.retry.Path)
+		q.run.execute([]syncPart{retryPart(cfg, *q.retry)})
+	case q.items != nil:
+		fmt.Printf("\n%s Starting %s sync of %d subtitles\n", time.Now().Format("2006-01-02 15:04:05"), q.run.Trigger, len(q.items))
+		if cfg.Cache.Enabled {
+			Load_cache(cfg)
+		}
+		q.run.execute([]syncPart{historyItemsPart(cfg, q.items)})
+	default:
+		runAdhocSync(q.run, cfg, *q.request)
+	}
+}
+
+// recordRunMetrics records a finished run under its job name, or under its
+// trigger for runs outside the schedule.
+func recordRunMetrics(run *syncRun) {
+	job := run.Job
+	if job == "" {
+		job = run.Trigger
+	}
+	snap := run.snapshot()
+	metrics.RecordRun(job, snap.Finished, !run.Failed() && !run.Cancelled(), metrics.RunCounts(snap.Summary))
+}
+
+// runAdhocSync runs a sync requested through the control API.
+func runAdhocSync(run *syncRun, cfg config.Config, req server.SyncRequest) {
+	fmt.Printf("\n%s Starting %s sync\n", time.Now().Format("2006-01-02 15:04:05"), run.Trigger)
+
+	if cfg.Cache.Enabled {
+		Load_cache(cfg)
+	}
+
+	var parts []syncPart
+	if req.Shows {
+		sel := selection{Ids: req.SonarrIds, ContinueFrom: -1}
+		parts = append(parts, announcedPart("\n📺 Syncing TV shows...", showsPart(cfg, sel)))
+	}
+	if req.Movies {
+		sel := selection{Ids: req.RadarrIds, ContinueFrom: -1}
+		parts = append(parts, announcedPart("\n🎬 Syncing movies...", moviesPart(cfg, sel)))
+	}
+	run.execute(parts)
+}
+
+// Jobs implements server.Controller.
+func (s *scheduler) Jobs() []server.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := []server.Job{}
+	for _, job := range s.jobs {
+		entry := s.cron.Entry(job.entry)
+		jobs = append(jobs, server.Job{
+			Name:     job.Name,
+			Schedule: job.Spec,
+			Full:     job.Full,
+			NextRun:  entry.Next,
+			LastRun:  entry.Prev,
+		})
+	}
+	return jobs
+}
+
+// RunJob implements server.Controller.
+func (s *scheduler) RunJob(name string) (string, error) {
+	return s.runJob(name, "api")
+}
+
+// runJob queues a run of a scheduled job outside its schedule.
+func (s *scheduler) runJob(name string, trigger string) (string, error) {
+	s.mu.Lock()
+	var found *scheduledJob
+	for _, job := range s.jobs {
+		if job.Name == name {
+			found = &job
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if found == nil {
+		return "", fmt.Errorf("%w: %s", server.ErrUnknownJob, name)
+	}
+	return s.enqueueJob(*found, trigger), nil
+}
+
+// StartSync implements server.Controller.
+func (s *scheduler) StartSync(req server.SyncRequest) (string, error) {
+	if len(req.RadarrIds) > 0 {
+		req.Movies = true
+	}
+	if len(req.SonarrIds) > 0 {
+		req.Shows = true
+	}
+	if !req.Movies && !req.Shows {
+		return "", fmt.Errorf("%w: select movies, shows or specific IDs", server.ErrInvalidRequest)
+	}
+	return s.enqueue(&queuedRun{run: newRun("api", ""), request: &req}), nil
+}
+
+// SyncSubtitle implements server.Controller.
+func (s *scheduler) SyncSubtitle(req server.SubtitleRequest) (string, error) {
+	item, err := subtitleItem(req)
+	if err != nil {
+		return "", err
+	}
+	return s.enqueue(&queuedRun{run: newRun("hook", ""), items: []historyItem{item}}), nil
+}
+
+// Current implements server.Controller.
+func (s *scheduler) Current() (server.Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil {
+		return server.Run{}, server.ErrNotRunning
+	}
+	return s.current.status(true), nil
+}
+
+// Cancel implements server.Controller.
+func (s *scheduler) Cancel() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil {
+		return "", server.ErrNotRunning
+	}
+	s.current.run.Cancel()
+	return s.current.run.ID, nil
+}
+
+// Runs implements server.Controller.
+func (s *scheduler) Runs() []server.Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := []server.Run{}
+	for i := len(s.queue) - 1; i >= 0; i-- {
+		runs = append(runs, s.queue[i].status(false))
+	}
+	if s.current != nil {
+		runs = append(runs, s.current.status(true))
+	}
+	for _, q := range s.recent {
+		runs = append(runs, q.status(false))
+	}
+	return runs
+}
+
+// status converts the run to its control API representation.
+func (q *queuedRun) status(running bool) server.Run {
+	snap := q.run.snapshot()
+	status := server.Run{
+		Id:      q.run.ID,
+		Trigger: q.run.Trigger,
+		Job:     q.run.Job,
+		Queued:  q.queued,
+		Summary: server.Summary(snap.Summary),
+	}
+	for _, part := range snap.Parts {
+		p := server.Part{Name: part.Name, Summary: server.Summary(part.Summary)}
+		if part.Err != nil {
+			p.Error = part.Err.Error()
+		}
+		status.Parts = append(status.Parts, p)
+	}
+	if !snap.Started.IsZero() {
+		status.Started = &snap.Started
+	}
+	if !snap.Finished.IsZero() {
+		status.Finished = &snap.Finished
+	}
+
+	switch {
+	case running:
+		status.Status = server.StatusRunning
+		status.Progress = &server.Progress{Part: snap.Part, Position: snap.Position, Total: snap.Total, Current: snap.Current}
+	case snap.Started.IsZero():
+		status.Status = server.StatusQueued
+	case q.run.Cancelled():
+		status.Status = server.StatusCancelled
+	case q.run.Failed():
+		status.Status = server.StatusFailed
+	default:
+		status.Status = server.StatusCompleted
+	}
+	return status
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/report"
+)
+
+// --report of the sync commands, empty for none
+var reportFile string
+
+func init() {
+	onRunFinished(writeReport)
+}
+
+// setupReport checks the --report file name before anything is synced.
+func setupReport() error {
+	if reportFile == "" {
+		return nil
+	}
+	_, err := report.Format(reportFile)
+	return err
+}
+
+// writeReport writes the report of a finished run: to --report for the run
+// of a sync command, or to Report.File for the scheduled jobs in
+// Report.Jobs.
+func writeReport(run *syncRun) {
+	path := reportFile
+	if path == "" {
+		cfg := config.GetConfig().Report
+		if cfg.File == "" || run.Job == "" || (len(cfg.Jobs) > 0 && !slices.Contains(cfg.Jobs, run.Job)) {
+			return
+		}
+		path = cfg.File
+	}
+
+	job := run.Job
+	if job == "" {
+		job = run.Trigger
+	}
+	path = strings.NewReplacer(
+		"{date}", run.Started.Format("2006-01-02"),
+		"{job}", job,
+		"{run_id}", run.ID,
+	).Replace(path)
+
+	if err := report.Write(path, newRecordedRun(run)); err != nil {
+		run.logger().Error("Could not write the report", "path", path, "err", err)
+		return
+	}
+	run.logger().Info("Report written", "path", path)
+}
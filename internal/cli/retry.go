@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/regix1/bazarr-sync/internal/bazarr"
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var retryRunId string
+
+var retryFailedCmd = &cobra.Command{
+	Use:   "retry-failed",
+	Short: "Sync only the subtitles that failed in a previous run",
+	Example: `  bazarr-sync retry-failed
+  bazarr-sync retry-failed --run 20261018-010000-3f2a --golden-section`,
+	Long: `Takes the subtitles that failed in the last run, or the run given with --run, from the run
+history and syncs only those. Sync options such as --golden-section can differ from the
+original run. The retry is recorded as a new run that refers to the original one.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.GetConfig()
+
+		// Override config with command line flags
+		applyFlagOverrides(cmd, &cfg)
+		checkConfig(cfg)
+
+		original, err := openHistory(cfg).Get(retryRunId)
+		if err != nil {
+			slog.Error("Could not load the run", "run_id", retryRunId, "err", err)
+			exit(exitError)
+		}
+
+		var refs []subtitleRef
+		for _, sub := range original.Subtitles {
+			if sub.Outcome != outcomeFailed.String() {
+				continue
+			}
+			refs = append(refs, subtitleRef{Kind: sub.Kind, Id: sub.Id, Title: sub.Title, Media: sub.Media,
+				Language: sub.Language, Path: sub.Path})
+		}
+		if len(refs) == 0 {
+			fmt.Printf("No failed subtitles in run %s.\n", original.Id)
+			return
+		}
+
+		if err := bazarr.HealthCheck(cfg); err != nil {
+			exit(errorExitCode(err))
+		}
+		// Synced subtitles are added to the cache, which is rewritten as
+		// a whole
+		if cfg.Cache.Enabled {
+			Load_cache(cfg)
+		}
+		fmt.Printf("Retrying %d failed subtitles of run %s.\n", len(refs), original.Id)
+
+		run := newRun("retry", "")
+		run.RetryOf = original.Id
+
+		// Stop after the subtitle being synced, so the retry is still
+		// recorded
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(sigChan)
+		go func() {
+			<-sigChan
+			fmt.Println("\nStopping after the current subtitle...")
+			run.Cancel()
+		}()
+
+		run.execute([]syncPart{retryPart(cfg, refs...)})
+		exitForRun(run)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(retryFailedCmd)
+	retryFailedCmd.Flags().StringVar(&retryRunId, "run", "last", "ID of the run whose failures are retried")
+	retryFailedCmd.Flags().StringVar(&reportFile, "report", "", "Write a report of the run to this .html, .md or .csv file")
+}
@@ -3,12 +3,16 @@ package cli
 import (
 	"bufio"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/logging"
 	"github.com/spf13/cobra"
 )
 
@@ -21,6 +25,10 @@ var schedule bool
 var movies_cache = make(map[string]bool)
 var shows_cache = make(map[string]bool)
 
+// cacheMu guards the caches against readers outside the sync, such as the
+// dashboard. The sync itself is the only writer.
+var cacheMu sync.RWMutex
+
 var rootCmd = &cobra.Command{
 	Use:     "bazarr-sync",
 	Aliases: []string{"bs"},
@@ -35,19 +43,37 @@ This cli tool helps you achieve that by utilizing bazarr's api.`,
   bazarr-sync sync shows
   bazarr-sync cancel
   bazarr-sync --schedule`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if err := setupLogging(config.GetConfig()); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			exit(exitConfig)
+		}
+		if err := setupList(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			exit(exitError)
+		}
+		if err := setupOutput(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			exit(exitError)
+		}
+		if err := setupProgress(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			exit(exitError)
+		}
+		if err := setupReport(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			exit(exitError)
+		}
+		if err := setupExit(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			exit(exitError)
+		}
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := config.GetConfig()
 
 		// Override config with command line flags if provided
-		if cmd.Flags().Changed("golden-section") {
-			cfg.SyncOptions.GoldenSection = gss
-		}
-		if cmd.Flags().Changed("no-framerate-fix") {
-			cfg.SyncOptions.NoFramerateFix = no_framerate_fix
-		}
-		if cmd.Flags().Changed("use-cache") {
-			cfg.Cache.Enabled = use_cache
-		}
+		applyFlagOverrides(cmd, &cfg)
 
 		// Load cache if enabled
 		if cfg.Cache.Enabled {
@@ -56,7 +82,7 @@ This cli tool helps you achieve that by utilizing bazarr's api.`,
 
 		// Run scheduler if enabled in config or via flag
 		if schedule || cfg.Schedule.Enabled {
-			RunScheduler(cfg)
+			RunScheduler(cmd, cfg)
 		} else {
 			// Show help if no subcommand
 			cmd.Help()
@@ -66,6 +92,7 @@ This cli tool helps you achieve that by utilizing bazarr's api.`,
 
 func Execute() {
 	err := rootCmd.Execute()
+	logging.Close()
 	if err != nil {
 		os.Exit(1)
 	}
@@ -73,16 +100,36 @@ func Execute() {
 
 func init() {
 	cobra.OnInitialize(func() {
+		bootstrapLogging()
 		config.InitConfig()
 	})
 
 	rootCmd.PersistentFlags().StringVar(&config.CfgFile, "config", "", "config file (default is ./config.yaml)")
 	rootCmd.PersistentFlags().BoolVar(&gss, "golden-section", false, "Use Golden-Section Search")
 	rootCmd.PersistentFlags().BoolVar(&no_framerate_fix, "no-framerate-fix", false, "Don't try to fix framerate")
-	rootCmd.PersistentFlags().BoolVar(&to_list, "list", false, "List your media with their respective Radarr/Sonarr id")
+	rootCmd.PersistentFlags().BoolVar(&to_list, "list", false, "List your media with their respective Radarr/Sonarr id, or the episodes of the shows given with --sonarr-id")
 	rootCmd.PersistentFlags().BoolVar(&use_cache, "use-cache", false, "Use cache to skip already synced subtitles")
 	rootCmd.PersistentFlags().BoolVar(&schedule, "schedule", false, "Run on schedule defined in config file")
 	rootCmd.PersistentFlags().BoolVar(&runInitial, "run-initial", false, "Run initial sync when starting scheduler")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputText, "Output format: text, json (summary per run) or ndjson (one event per line)")
+	rootCmd.PersistentFlags().StringVar(&progressFlag, "progress", progressAuto, "Progress display: auto, fancy (live bar), plain (one line per outcome, for logs) or none")
+	rootCmd.PersistentFlags().StringVar(&failOnFlag, "fail-on", "0", "Exit with an error when more subtitles fail than this: a number (3) or a percentage of the subtitles tried (5%)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Log level: debug, info, warn or error (default from config, info)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "Log format: text or json (default from config, text)")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Also write the log to this file, rotated by size (default from config)")
+}
+
+// applyFlagOverrides lets command line flags take precedence over the config file.
+func applyFlagOverrides(cmd *cobra.Command, cfg *config.Config) {
+	if cmd.Flags().Changed("golden-section") {
+		cfg.SyncOptions.GoldenSection = gss
+	}
+	if cmd.Flags().Changed("no-framerate-fix") {
+		cfg.SyncOptions.NoFramerateFix = no_framerate_fix
+	}
+	if cmd.Flags().Changed("use-cache") {
+		cfg.Cache.Enabled = use_cache
+	}
 }
 
 func Load_cache(cfg config.Config) {
@@ -93,12 +140,15 @@ func Load_cache(cfg config.Config) {
 	movies_cache_file, err := os.Open(cfg.Cache.MoviesCache)
 	if err != nil {
 		if !os.IsNotExist(err) {
-			fmt.Fprintln(os.Stderr, "Error opening movies cache file:", err)
+			slog.Error("Could not open movies cache file", "path", cfg.Cache.MoviesCache, "err", err)
 		}
 		return
 	}
 	defer movies_cache_file.Close()
 
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
 	scanner := bufio.NewScanner(movies_cache_file)
 	for scanner.Scan() {
 		movies_cache[scanner.Text()] = true
@@ -107,7 +157,7 @@ func Load_cache(cfg config.Config) {
 	shows_cache_file, err := os.Open(cfg.Cache.ShowsCache)
 	if err != nil {
 		if !os.IsNotExist(err) {
-			fmt.Fprintln(os.Stderr, "Error opening shows cache file:", err)
+			slog.Error("Could not open shows cache file", "path", cfg.Cache.ShowsCache, "err", err)
 		}
 		return
 	}
@@ -123,10 +173,13 @@ func Write_movies_cache(cfg config.Config, key string) {
 	if !cfg.Cache.Enabled {
 		return
 	}
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
 	movies_cache[key] = true
 	file, err := os.Create(cfg.Cache.MoviesCache)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error writing movies cache file:", err)
+		slog.Error("Could not write movies cache file", "path", cfg.Cache.MoviesCache, "err", err)
 		return
 	}
 	defer file.Close()
@@ -139,10 +192,13 @@ func Write_shows_cache(cfg config.Config, key string) {
 	if !cfg.Cache.Enabled {
 		return
 	}
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
 	shows_cache[key] = true
 	file, err := os.Create(cfg.Cache.ShowsCache)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error writing shows cache file:", err)
+		slog.Error("Could not write shows cache file", "path", cfg.Cache.ShowsCache, "err", err)
 		return
 	}
 	defer file.Close()
@@ -151,29 +207,39 @@ func Write_shows_cache(cfg config.Config, key string) {
 	}
 }
 
-func runWithSignalHandler(syncFunc func(chan int)) {
+// runWithSignalHandler executes a run and, if it is interrupted, cancels it
+// after the subtitle being synced and tells the user how to continue from
+// the last processed item of the given kind. It returns once the run has
+// finished, so even an interrupted run is recorded.
+func runWithSignalHandler(run *syncRun, kind string, parts []syncPart) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	var lastSubtitleId atomic.Int64
+	lastSubtitleId.Store(-1)
+	run.observers = append(run.observers, func(ev syncEvent) {
+		if ev.Kind == kind && ev.Id != 0 {
+			lastSubtitleId.Store(int64(ev.Id))
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run.execute(parts)
+	}()
 
-	progressChan := make(chan int, 1)
-	go syncFunc(progressChan)
-
-	lastSubtitleId := -1
-	for {
-		select {
-		case subtitle, more := <-progressChan:
-			if !more {
-				return
-			}
-			lastSubtitleId = subtitle
-		case <-sigChan:
-			if lastSubtitleId != -1 {
-				showContinueMessage(lastSubtitleId)
-			} else {
-				fmt.Println("Stopping current sync. No subtitles have been processed yet.")
-			}
-			return
+	select {
+	case <-done:
+	case <-sigChan:
+		if id := lastSubtitleId.Load(); id != -1 {
+			showContinueMessage(int(id))
+		} else {
+			fmt.Println("Stopping current sync. No subtitles have been processed yet.")
 		}
+		run.Cancel()
+		<-done
 	}
 }
 
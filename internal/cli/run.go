@@ -0,0 +1,464 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/history"
+)
+
+// Event types emitted while a run progresses
+const (
+	eventRunStarted   = "run_started"
+	eventPartStarted  = "part_started"
+	eventItem         = "item"    // a movie, series or history item is being processed
+	eventSyncing      = "syncing" // a subtitle sync request was sent
+	eventRetry        = "retry"   // a failed sync is tried once more
+	eventOutcome      = "outcome" // a subtitle sync finished
+	eventSkipped      = "skipped" // a subtitle was not synced
+	eventPartFinished = "part_finished"
+	eventRunFinished  = "run_finished"
+)
+
+// syncEvent describes one step of a run. Which fields are set depends on the
+// type; subtitle events carry the media kind, ID, language and path.
+type syncEvent struct {
+	Type     string       `json:"type"`
+	Time     time.Time    `json:"time"`
+	RunId    string       `json:"run_id"`
+	Part     string       `json:"part,omitempty"`
+	Kind     string       `json:"kind,omitempty"`
+	Id       int          `json:"id,omitempty"`
+	Title    string       `json:"title,omitempty"`
+	Media    string       `json:"media,omitempty"`
+	Language string       `json:"language,omitempty"`
+	Path     string       `json:"path,omitempty"`
+	Outcome  string       `json:"outcome,omitempty"`
+	Message  string       `json:"message,omitempty"`
+	Position int          `json:"position,omitempty"`
+	Total    int          `json:"total,omitempty"`
+	Retries  int          `json:"retries,omitempty"`
+	Summary  *syncSummary `json:"summary,omitempty"`
+}
+
+// subtitleRef identifies a subtitle in events.
+type subtitleRef struct {
+	Kind     string // "movie" or "episode", as used by the sync API
+	Id       int
+	Title    string
+	Media    string // movie or series the subtitle belongs to
+	Language string
+	Path     string
+}
+
+// subtitle returns the subtitle a subtitle event is about.
+func (ev syncEvent) subtitle() subtitleRef {
+	return subtitleRef{Kind: ev.Kind, Id: ev.Id, Title: ev.Title, Media: ev.Media, Language: ev.Language, Path: ev.Path}
+}
+
+// runObservers receive the events of every run.
+var runObservers []func(syncEvent)
+var runObserversMu sync.Mutex
+
+func addRunObserver(observer func(syncEvent)) {
+	runObserversMu.Lock()
+	defer runObserversMu.Unlock()
+	runObservers = append(runObservers, observer)
+}
+
+// runFinishedHooks are called with every run once it has finished.
+var runFinishedHooks []func(*syncRun)
+
+func onRunFinished(hook func(*syncRun)) {
+	runObserversMu.Lock()
+	defer runObserversMu.Unlock()
+	runFinishedHooks = append(runFinishedHooks, hook)
+}
+
+// subtitleFailure is a subtitle that failed to sync during a run.
+type subtitleFailure struct {
+	subtitleRef
+	Message string
+}
+
+// subtitleOutcome is what happened to a subtitle during a run: one of the
+// sync outcomes, or skipped. Message holds the error or the reason for
+// skipping it.
+type subtitleOutcome struct {
+	subtitleRef
+	Part    string
+	Outcome string
+	Message string
+	Time    time.Time
+	// How long Bazarr took to sync it, retries included; 0 when skipped
+	Duration time.Duration
+}
+
+// syncPart is one independent pass of a run, such as all movies or all shows.
+type syncPart struct {
+	Name string
+	Sync func(run *syncRun) error
+	// Settings the part runs with, kept in the run history
+	Options history.Options
+}
+
+// partResult is the outcome of one part of a run. Err is set when the part
+// could not finish, for example because Bazarr could not be queried.
+type partResult struct {
+	Name     string
+	Options  history.Options
+	Started  time.Time
+	Finished time.Time
+	Summary  syncSummary
+	Err      error
+}
+
+// syncRun records a run made of one or more parts. Parts report their
+// progress through emit, which keeps the live counters and forwards the
+// events to observers.
+type syncRun struct {
+	ID      string
+	Trigger string
+	Job     string
+	// Run whose failures this run retries, if any
+	RetryOf  string
+	Started  time.Time
+	Finished time.Time
+	Parts    []partResult
+	Summary  syncSummary
+	Synced   []subtitleRef
+	Failures []subtitleFailure
+	Outcomes []subtitleOutcome
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	observers []func(syncEvent)
+
+	mu       sync.Mutex
+	part     *partResult
+	position int
+	total    int
+	current  string
+	syncing  time.Time // when the subtitle being synced was sent
+}
+
+func newRun(trigger string, job string) *syncRun {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &syncRun{
+		ID:      newRunId(),
+		Trigger: trigger,
+		Job:     job,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// newRunId returns a sortable, practically unique run ID.
+func newRunId() string {
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// Failed reports whether any part of the run could not finish.
+func (r *syncRun) Failed() bool {
+	for _, part := range r.Parts {
+		if part.Err != nil && !errors.Is(part.Err, context.Canceled) {
+			return true
+		}
+	}
+	return false
+}
+
+// Cancelled reports whether the run was stopped before it finished.
+func (r *syncRun) Cancelled() bool {
+	return r.ctx.Err() != nil
+}
+
+// status describes how a finished run ended: completed, failed or
+// cancelled.
+func (r *syncRun) status() string {
+	switch {
+	case r.Cancelled():
+		return "cancelled"
+	case r.Failed():
+		return "failed"
+	}
+	return "completed"
+}
+
+// Cancel asks the run to stop after the subtitle being synced.
+func (r *syncRun) Cancel() {
+	r.cancel()
+}
+
+// stopped returns an error once the run has been cancelled. Parts check it
+// between subtitles.
+func (r *syncRun) stopped() error {
+	return r.ctx.Err()
+}
+
+func (r *syncRun) emit(ev syncEvent) {
+	ev.Time = time.Now()
+	ev.RunId = r.ID
+
+	r.mu.Lock()
+	if ev.Part == "" && r.part != nil {
+		ev.Part = r.part.Name
+	}
+	switch ev.Type {
+	case eventItem:
+		r.position, r.total, r.current = ev.Position, ev.Total, ev.Title
+	case eventSyncing:
+		r.syncing = ev.Time
+	case eventOutcome:
+		outcome := parseOutcome(ev.Outcome)
+		r.part.Summary.record(outcome)
+		r.Summary.record(outcome)
+		ref := ev.subtitle()
+		switch outcome {
+		case outcomeSuccess:
+			r.Synced = append(r.Synced, ref)
+		case outcomeFailed:
+			r.Failures = append(r.Failures, subtitleFailure{subtitleRef: ref, Message: ev.Message})
+		}
+		var duration time.Duration
+		if !r.syncing.IsZero() {
+			duration = ev.Time.Sub(r.syncing)
+		}
+		r.syncing = time.Time{}
+		r.Outcomes = append(r.Outcomes, subtitleOutcome{subtitleRef: ref, Part: ev.Part,
+			Outcome: ev.Outcome, Message: ev.Message, Time: ev.Time, Duration: duration})
+	case eventSkipped:
+		r.part.Summary.Skipped++
+		r.Summary.Skipped++
+		r.Outcomes = append(r.Outcomes, subtitleOutcome{subtitleRef: ev.subtitle(), Part: ev.Part,
+			Outcome: "skipped", Message: ev.Message, Time: ev.Time})
+	}
+	observers := append([]func(syncEvent){}, r.observers...)
+	r.mu.Unlock()
+
+	runObserversMu.Lock()
+	observers = append(observers, runObservers...)
+	runObserversMu.Unlock()
+
+	for _, observer := range observers {
+		observer(ev)
+	}
+	r.logEvent(ev)
+}
+
+// item reports that processing of a movie, series or history item started.
+func (r *syncRun) item(kind string, id int, title string, position int, total int) {
+	r.emit(syncEvent{Type: eventItem, Kind: kind, Id: id, Title: title, Position: position, Total: total})
+}
+
+// skip reports a subtitle that is not synced and why.
+func (r *syncRun) skip(ref subtitleRef, reason string) {
+	r.emit(syncEvent{Type: eventSkipped, Kind: ref.Kind, Id: ref.Id, Title: ref.Title, Media: ref.Media,
+		Language: ref.Language, Path: ref.Path, Message: reason})
+}
+
+// runSnapshot is a copy of a run's record that is safe to use while the run
+// goes on.
+type runSnapshot struct {
+	Started  time.Time
+	Finished time.Time
+	Parts    []partResult
+	Summary  syncSummary
+	Synced   []subtitleRef
+	Failures []subtitleFailure
+	Outcomes []subtitleOutcome
+
+	// Progress of the part in progress
+	Part     string
+	Position int
+	Total    int
+	Current  string
+}
+
+func (r *syncRun) snapshot() runSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snap := runSnapshot{
+		Started:  r.Started,
+		Finished: r.Finished,
+		Parts:    append([]partResult{}, r.Parts...),
+		Summary:  r.Summary,
+		Synced:   append([]subtitleRef{}, r.Synced...),
+		Failures: append([]subtitleFailure{}, r.Failures...),
+		Outcomes: append([]subtitleOutcome{}, r.Outcomes...),
+		Position: r.position,
+		Total:    r.total,
+		Current:  r.current,
+	}
+	if r.part != nil {
+		snap.Part = r.part.Name
+	}
+	return snap
+}
+
+func moviesPart(cfg config.Config, sel selection) syncPart {
+	return syncPart{
+		Name: "movies",
+		Sync: func(run *syncRun) error {
+			return sync_movies(run, cfg, sel)
+		},
+		Options: partOptions(cfg, sel),
+	}
+}
+
+func showsPart(cfg config.Config, sel selection) syncPart {
+	return syncPart{
+		Name: "shows",
+		Sync: func(run *syncRun) error {
+			return sync_shows(run, cfg, sel)
+		},
+		Options: partOptions(cfg, sel),
+	}
+}
+
+func historyPart(cfg config.Config, since time.Time) syncPart {
+	opts := partOptions(cfg, allSelection)
+	opts.Since = since.Format(time.RFC3339)
+	return syncPart{
+		Name: "history",
+		Sync: func(run *syncRun) error {
+			return sync_history(run, cfg, since)
+		},
+		Options: opts,
+	}
+}
+
+// partOptions returns the settings a part with the selection runs with.
+func partOptions(cfg config.Config, sel selection) history.Options {
+	opts := history.Options{
+		GoldenSection:  cfg.SyncOptions.GoldenSection,
+		NoFramerateFix: cfg.SyncOptions.NoFramerateFix,
+		Cache:          cfg.Cache.Enabled,
+		Ids:            sel.Ids,
+	}
+	if sel.ContinueFrom > 0 {
+		opts.ContinueFrom = sel.ContinueFrom
+	}
+	if sel.Filter != nil {
+		if !sel.Filter.since.IsZero() {
+			opts.Since = sel.Filter.since.Format(time.RFC3339)
+		}
+		if !sel.Filter.until.IsZero() {
+			opts.Until = sel.Filter.until.Format(time.RFC3339)
+		}
+	}
+	return opts
+}
+
+// executeRun creates a run and executes the parts one after another.
+func executeRun(trigger string, parts []syncPart, observers ...func(syncEvent)) *syncRun {
+	run := newRun(trigger, "")
+	run.observers = observers
+	run.execute(parts)
+	return run
+}
+
+// execute runs the parts one after another. A part that fails, or even
+// panics, is recorded and does not stop the parts after it; cancelling the
+// run does. When there is more than one part a combined summary is printed
+// at the end.
+func (r *syncRun) execute(parts []syncPart) {
+	r.mu.Lock()
+	r.Started = time.Now()
+	r.mu.Unlock()
+	r.emit(syncEvent{Type: eventRunStarted, Message: r.Trigger})
+
+	for _, part := range parts {
+		if r.stopped() != nil {
+			break
+		}
+
+		r.mu.Lock()
+		r.Parts = append(r.Parts, partResult{Name: part.Name, Options: part.Options, Started: time.Now()})
+		r.part = &r.Parts[len(r.Parts)-1]
+		r.position, r.total, r.current = 0, 0, ""
+		r.mu.Unlock()
+
+		r.emit(syncEvent{Type: eventPartStarted})
+		err := r.runPart(part)
+
+		r.mu.Lock()
+		r.part.Err = err
+		r.part.Finished = time.Now()
+		summary := r.part.Summary
+		r.mu.Unlock()
+
+		// Observers hear about the end first, so a progress bar is gone
+		// before the summary is printed
+		ev := syncEvent{Type: eventPartFinished, Summary: &summary}
+		if err != nil {
+			ev.Message = err.Error()
+		}
+		r.emit(ev)
+
+		// A part that failed was logged with its error
+		switch {
+		case errors.Is(err, context.Canceled):
+			summary.print()
+			fmt.Println("🛑 Sync cancelled.")
+		case err == nil:
+			summary.print()
+		}
+	}
+
+	r.mu.Lock()
+	r.part = nil
+	r.Finished = time.Now()
+	summary := r.Summary
+	r.mu.Unlock()
+
+	if len(parts) > 1 {
+		r.print()
+	}
+	r.emit(syncEvent{Type: eventRunFinished, Summary: &summary})
+
+	runObserversMu.Lock()
+	hooks := append([]func(*syncRun){}, runFinishedHooks...)
+	runObserversMu.Unlock()
+	for _, hook := range hooks {
+		hook(r)
+	}
+}
+
+func (r *syncRun) runPart(part syncPart) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic: %v", rec)
+		}
+	}()
+	return part.Sync(r)
+}
+
+// print shows the combined summary of a run with more than one part.
+func (r *syncRun) print() {
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Run summary:\n")
+	for _, part := range r.Parts {
+		if part.Err != nil && !errors.Is(part.Err, context.Canceled) {
+			fmt.Printf("  %-8s ❌ did not complete: %v\n", part.Name, part.Err)
+			continue
+		}
+		fmt.Printf("  %-8s %d synced, %d already in sync, %d skipped, %d failed\n", part.Name,
+			part.Summary.Success, part.Summary.AlreadySynced, part.Summary.Skipped, part.Summary.Failed)
+	}
+	fmt.Printf("  %-8s %d synced, %d already in sync, %d skipped, %d failed\n", "total",
+		r.Summary.Success, r.Summary.AlreadySynced, r.Summary.Skipped, r.Summary.Failed)
+	if r.Cancelled() {
+		fmt.Println("  🛑 cancelled before all parts finished")
+	}
+}
@@ -0,0 +1,329 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var historyLimit int
+var historyShowAll bool
+var failuresRuns int
+var failuresAll bool
+var failuresMin int
+
+var historyCmd = &cobra.Command{
+	Use:     "history",
+	Aliases: []string{"runs"},
+	Short:   "List past sync runs",
+	Example: `  bazarr-sync history
+  bazarr-sync history show last
+  bazarr-sync history failures`,
+	Long: `Every run is recorded in the History.Dir directory of the config: when and why it ran, with
+which options, and what happened to each subtitle. Old runs are removed according to
+History.MaxRuns and History.MaxAge.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openHistory(config.GetConfig())
+		entries, err := store.List(historyLimit)
+		if err != nil {
+			slog.Error("Could not read the run history", "err", err)
+			exit(exitError)
+		}
+
+		if structuredOutput() {
+			writeList(entries)
+			return
+		}
+		if len(entries) == 0 {
+			fmt.Println("No runs recorded yet.")
+			return
+		}
+
+		fmt.Printf("%-22s %-19s %-9s %-9s %-10s %9s %7s %8s %8s %7s\n",
+			"Run", "Started", "Trigger", "Job", "Status", "Duration", "Synced", "In sync", "Skipped", "Failed")
+		fmt.Println(strings.Repeat("-", 118))
+		for _, e := range entries {
+			fmt.Printf("%-22s %-19s %-9s %-9s %-10s %9s %7d %8d %8d %7d\n",
+				e.Id, e.Started.Local().Format(time.DateTime), e.Trigger, e.Job, e.Status,
+				e.Duration().Round(time.Second), e.Summary.Success, e.Summary.AlreadySynced,
+				e.Summary.Skipped, e.Summary.Failed)
+		}
+		fmt.Printf("\nTotal: %d runs\n", len(entries))
+	},
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <run>",
+	Short: "Show the details of a run",
+	Long: `Shows a run with its options and the subtitles that were synced or failed. The run is
+given by its ID, a unique start of it, or "last". --all also lists the subtitles that were
+already in sync or skipped.`,
+	Example: `  bazarr-sync history show 20261018-150405-1a2b
+  bazarr-sync history show last --all`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openHistory(config.GetConfig())
+		run, err := store.Get(args[0])
+		if err != nil {
+			slog.Error("Could not load the run", "run_id", args[0], "err", err)
+			exit(exitError)
+		}
+
+		if structuredOutput() {
+			writeStructured(run)
+			return
+		}
+		printRecordedRun(run, historyShowAll)
+	},
+}
+
+var historyFailuresCmd = &cobra.Command{
+	Use:   "failures",
+	Short: "List the subtitles that keep failing to sync",
+	Long: `Aggregates the failed subtitles of the recorded runs, the ones that failed most often first.
+By default only subtitles whose latest sync still failed are listed; --all includes the ones
+that have synced since. Subtitles that failed in History.QuarantineAfter runs in a row are
+quarantined.`,
+	Example: `  bazarr-sync history failures
+  bazarr-sync history failures --min 3 --runs 20`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.GetConfig()
+		store := openHistory(cfg)
+		runs, err := store.Runs(failuresRuns)
+		if err != nil {
+			slog.Error("Could not read the run history", "err", err)
+			exit(exitError)
+		}
+
+		var failures []history.Failure
+		for _, f := range history.Failures(runs) {
+			if f.Failures >= failuresMin && (f.Failed || failuresAll) {
+				failures = append(failures, f)
+			}
+		}
+
+		if structuredOutput() {
+			writeList(failures)
+			return
+		}
+		if len(failures) == 0 {
+			fmt.Printf("No failures in the last %d runs.\n", len(runs))
+			return
+		}
+
+		fmt.Printf("%-8s %-16s %-11s %s\n", "Failed", "Last failed", "Status", "Subtitle")
+		fmt.Println(strings.Repeat("-", 80))
+		for _, f := range failures {
+			status := "failing"
+			switch {
+			case !f.Failed:
+				status = "fixed"
+			case f.Quarantined(cfg.History.QuarantineAfter):
+				status = "quarantined"
+			}
+			fmt.Printf("%-8s %-16s %-11s %s\n", fmt.Sprintf("%d/%d", f.Failures, f.Attempts),
+				f.LastFailed.Local().Format("2006-01-02 15:04"), status, subtitleLabel(f.Title, f.Language))
+			fmt.Printf("%37s %s\n", "", f.Path)
+			fmt.Printf("%37s last error: %s (run %s)\n", "", f.LastMessage, f.LastRunId)
+		}
+		fmt.Printf("\nTotal: %d subtitles in %d runs\n", len(failures), len(runs))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyFailuresCmd)
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 20, "Number of runs to list, 0 for all")
+	historyShowCmd.Flags().BoolVar(&historyShowAll, "all", false, "Also list the subtitles that were already in sync or skipped")
+	historyFailuresCmd.Flags().IntVar(&failuresRuns, "runs", 0, "Only look at this many recent runs, 0 for all")
+	historyFailuresCmd.Flags().BoolVar(&failuresAll, "all", false, "Include subtitles that have synced since they failed")
+	historyFailuresCmd.Flags().IntVar(&failuresMin, "min", 1, "Only list subtitles that failed in at least this many runs")
+
+	onRunFinished(recordRun)
+}
+
+func openHistory(cfg config.Config) *history.Store {
+	if cfg.History.Dir == "" {
+		slog.Error("The run history is disabled, set History.Dir in the config to keep it")
+		exit(exitError)
+	}
+	return history.Open(cfg.History.Dir)
+}
+
+// recordRun saves a finished run to the history and removes the runs beyond
+// the retention limits.
+func recordRun(run *syncRun) {
+	cfg := config.GetConfig()
+	if cfg.History.Dir == "" {
+		return
+	}
+
+	store := history.Open(cfg.History.Dir)
+	if err := store.Save(newRecordedRun(run)); err != nil {
+		run.logger().Error("Could not record the run", "dir", cfg.History.Dir, "err", err)
+		return
+	}
+	if err := store.Prune(cfg.History.MaxRuns, cfg.History.MaxAge); err != nil {
+		slog.Warn("Could not remove old runs from the history", "dir", cfg.History.Dir, "err", err)
+	}
+}
+
+func newRecordedRun(run *syncRun) history.Run {
+	snap := run.snapshot()
+	rec := history.Run{
+		Entry: history.Entry{
+			Id:       run.ID,
+			Trigger:  run.Trigger,
+			Job:      run.Job,
+			RetryOf:  run.RetryOf,
+			Status:   run.status(),
+			Started:  snap.Started,
+			Finished: snap.Finished,
+			Summary:  history.Summary(snap.Summary),
+			Parts:    []history.Part{},
+		},
+		Subtitles: []history.Subtitle{},
+	}
+	for _, part := range snap.Parts {
+		p := history.Part{Name: part.Name, Options: part.Options, Started: part.Started, Finished: part.Finished,
+			Summary: history.Summary(part.Summary)}
+		if part.Err != nil {
+			p.Error = part.Err.Error()
+		}
+		rec.Parts = append(rec.Parts, p)
+	}
+	for _, o := range snap.Outcomes {
+		rec.Subtitles = append(rec.Subtitles, history.Subtitle{
+			Time:     o.Time,
+			Part:     o.Part,
+			Kind:     o.Kind,
+			Id:       o.Id,
+			Title:    o.Title,
+			Media:    o.Media,
+			Language: o.Language,
+			Path:     o.Path,
+			Outcome:  o.Outcome,
+			Message:  o.Message,
+			Duration: o.Duration.Seconds(),
+		})
+	}
+	return rec
+}
+
+// printRecordedRun shows a run from the history. Subtitles that were already
+// in sync or skipped are only listed with all.
+func printRecordedRun(run history.Run, all bool) {
+	fmt.Printf("Run %s\n", run.Id)
+	trigger := run.Trigger
+	if run.Job != "" {
+		trigger += " (job " + run.Job + ")"
+	}
+	fmt.Printf("  Trigger:  %s\n", trigger)
+	if run.RetryOf != "" {
+		fmt.Printf("  Retry of: %s\n", run.RetryOf)
+	}
+	fmt.Printf("  Status:   %s\n", run.Status)
+	fmt.Printf("  Started:  %s\n", run.Started.Local().Format("2006-01-02 15:04:05 MST"))
+	fmt.Printf("  Finished: %s (%s)\n", run.Finished.Local().Format("2006-01-02 15:04:05 MST"),
+		run.Duration().Round(time.Second))
+	fmt.Printf("  Result:   %s\n", describeSummary(run.Summary))
+
+	fmt.Println("\nParts:")
+	for _, part := range run.Parts {
+		fmt.Printf("  %-8s %s\n", part.Name, describeSummary(part.Summary))
+		if opts := describeOptions(part.Options); opts != "" {
+			fmt.Printf("  %-8s options: %s\n", "", opts)
+		}
+		if part.Error != "" {
+			fmt.Printf("  %-8s ❌ did not complete: %s\n", "", part.Error)
+		}
+	}
+
+	sections := []struct {
+		outcome string
+		heading string
+		icon    string
+	}{
+		{"failed", "Failed", "❌"},
+		{"success", "Synced", "✅"},
+		{"already_synced", "Already in sync", "✓"},
+		{"skipped", "Skipped", "⏭"},
+	}
+	for _, section := range sections {
+		if !all && (section.outcome == "already_synced" || section.outcome == "skipped") {
+			continue
+		}
+		var subs []history.Subtitle
+		for _, sub := range run.Subtitles {
+			if sub.Outcome == section.outcome {
+				subs = append(subs, sub)
+			}
+		}
+		if len(subs) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s (%d):\n", section.heading, len(subs))
+		for _, sub := range subs {
+			line := fmt.Sprintf("  %s %s", section.icon, subtitleLabel(sub.Title, sub.Language))
+			if sub.Message != "" && section.outcome != "success" {
+				line += " — " + sub.Message
+			}
+			fmt.Println(line)
+			if sub.Path != "" && section.outcome == "failed" {
+				fmt.Printf("     %s\n", sub.Path)
+			}
+		}
+	}
+}
+
+func subtitleLabel(title string, language string) string {
+	if language == "" {
+		return title
+	}
+	return fmt.Sprintf("%s [%s]", title, language)
+}
+
+func describeSummary(s history.Summary) string {
+	return fmt.Sprintf("%d synced, %d already in sync, %d skipped, %d failed",
+		s.Success, s.AlreadySynced, s.Skipped, s.Failed)
+}
+
+// describeOptions lists the options of a part that differ from a plain
+// sync of the whole library.
+func describeOptions(o history.Options) string {
+	var opts []string
+	if o.GoldenSection {
+		opts = append(opts, "golden-section")
+	}
+	if o.NoFramerateFix {
+		opts = append(opts, "no-framerate-fix")
+	}
+	if o.Cache {
+		opts = append(opts, "cache")
+	}
+	if len(o.Ids) > 0 {
+		ids := make([]string, len(o.Ids))
+		for i, id := range o.Ids {
+			ids[i] = fmt.Sprint(id)
+		}
+		opts = append(opts, "ids "+strings.Join(ids, ","))
+	}
+	if o.ContinueFrom > 0 {
+		opts = append(opts, fmt.Sprintf("continue from %d", o.ContinueFrom))
+	}
+	if o.Since != "" {
+		opts = append(opts, "since "+o.Since)
+	}
+	if o.Until != "" {
+		opts = append(opts, "until "+o.Until)
+	}
+	return strings.Join(opts, ", ")
+}
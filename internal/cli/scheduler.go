@@ -1,140 +1,360 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/pterm/pterm"
 	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/mqtt"
+	"github.com/regix1/bazarr-sync/internal/notify"
+	"github.com/regix1/bazarr-sync/internal/server"
+	"github.com/regix1/bazarr-sync/internal/state"
 	"github.com/robfig/cron/v3"
+	"github.com/spf13/cobra"
 )
 
-func RunScheduler(cfg config.Config) {
+// scheduledJob is one cron entry of the scheduler.
+type scheduledJob struct {
+	Name string
+	Spec string
+	// Full jobs always walk the whole library, even with incremental sync on
+	Full  bool
+	entry cron.EntryID
+}
+
+// scheduler runs sync jobs on the configured cron schedule and swaps in a new
+// configuration when the config file changes or SIGHUP is received. Runs,
+// scheduled or triggered through the control API, are queued and executed
+// one at a time.
+type scheduler struct {
+	cmd *cobra.Command
+	srv *server.Server
+
+	// jobMu is held while a sync job runs, so a reload only ever lands
+	// between jobs and never changes options under a running sync.
+	jobMu sync.Mutex
+
+	// mu guards everything below
+	mu      sync.Mutex
+	cfg     config.Config
+	cron    *cron.Cron
+	jobs    []scheduledJob
+	queue   []*queuedRun
+	current *queuedRun
+	recent  []*queuedRun
+	wake    chan struct{}
+	// Subtitles whose last sync failed, by path
+	failures map[string]server.Failure
+	// Set when state is published over MQTT
+	mqtt *mqtt.Client
+}
+
+func RunScheduler(cmd *cobra.Command, cfg config.Config) {
+	checkConfig(cfg)
 	if !cfg.Schedule.Enabled {
 		// Run once and exit
-		runSyncJobs(cfg)
+		run := newRun("schedule", "sync")
+		runSyncJobs(run, cfg, false)
+		exitForRun(run)
 		return
 	}
 
+	s := &scheduler{cmd: cmd, wake: make(chan struct{}, 1), failures: make(map[string]server.Failure)}
+	if err := s.start(cfg); err != nil {
+		slog.Error("Could not start the scheduler", "err", err)
+		exit(exitConfig)
+	}
+	s.printNextRun("Scheduler started.")
+	nextScheduledRun = s.nextRun
+	addRunObserver(s.recordOutcome)
+	go s.work()
+
+	if cfg.Server.Listen != "" {
+		s.srv = server.New(cfg.Server.Listen, cfg.Server.Token, s)
+		addr, err := s.srv.Start()
+		if err != nil {
+			slog.Error("Could not start control API", "listen", cfg.Server.Listen, "err", err)
+			exit(exitError)
+		}
+		addRunObserver(func(ev syncEvent) { s.srv.Publish(ev) })
+		s.serveMetrics()
+		slog.Info("Control API and dashboard listening", "addr", addr)
+		if cfg.Server.Token == "" {
+			slog.Warn("Server.Token is not set. Anyone who can reach the control API can use it.")
+		}
+	}
+
+	if cfg.Events.Enabled {
+		go s.followEvents(context.Background())
+	}
+
+	if cfg.Mqtt.Broker != "" {
+		s.startMqtt(cfg.Mqtt)
+	}
+
+	// Setup signal handling
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
+	// Reload whenever the config file is modified
+	reloadChan := make(chan struct{}, 1)
+	stopWatch, err := config.Watch(func() {
+		select {
+		case reloadChan <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		slog.Warn("Not watching config file for changes. Send SIGHUP to reload.", "err", err)
+	} else {
+		defer stopWatch()
+	}
+
+	// Run initial sync if requested
+	if runInitial {
+		slog.Info("Running initial sync")
+		s.enqueueJob(s.jobs[0], "initial")
+	}
+
+	for {
+		select {
+		case <-hupChan:
+			slog.Info("Received SIGHUP. Reloading configuration")
+			go s.reload()
+		case <-reloadChan:
+			slog.Info("Config file changed. Reloading configuration")
+			go s.reload()
+		case <-sigChan:
+			slog.Info("Received interrupt signal. Shutting down scheduler")
+			s.mu.Lock()
+			s.cron.Stop()
+			s.mu.Unlock()
+			if s.srv != nil {
+				s.srv.Shutdown()
+			}
+			if s.mqtt != nil {
+				s.mqtt.Close()
+			}
+			slog.Info("Scheduler stopped gracefully")
+			return
+		}
+	}
+}
+
+// start creates and starts a cron scheduler for cfg. A scheduler that is
+// already running must be stopped first. The caller must hold s.mu or be the
+// only goroutine using s.
+func (s *scheduler) start(cfg config.Config) error {
 	// Load timezone
 	location, err := time.LoadLocation(cfg.Schedule.Timezone)
 	if err != nil {
-		pterm.Error.Printf("Invalid timezone '%s': %v. Using UTC instead.\n", cfg.Schedule.Timezone, err)
+		slog.Error("Invalid timezone. Using UTC instead.", "timezone", cfg.Schedule.Timezone, "err", err)
 		location = time.UTC
 	}
 
 	// Create cron scheduler with timezone
 	c := cron.New(cron.WithLocation(location))
 
-	// Add scheduled job
-	_, err = c.AddFunc(cfg.Schedule.CronExpression, func() {
-		runSyncJobs(cfg)
-	})
+	// Add scheduled jobs, unless a reload turned scheduling off
+	var jobs []scheduledJob
+	if cfg.Schedule.Enabled {
+		jobs = append(jobs, scheduledJob{Name: "sync", Spec: cfg.Schedule.CronExpression, Full: !cfg.Schedule.Incremental})
 
-	if err != nil {
-		pterm.Error.Printf("Invalid cron expression '%s': %v\n", cfg.Schedule.CronExpression, err)
-		os.Exit(1)
+		// Periodic full pass as a safety net for incremental runs
+		if cfg.Schedule.Incremental && cfg.Schedule.FullSyncCron != "" {
+			jobs = append(jobs, scheduledJob{Name: "full-sync", Spec: cfg.Schedule.FullSyncCron, Full: true})
+		}
+	}
+	for i := range jobs {
+		job := jobs[i]
+		jobs[i].entry, err = c.AddFunc(job.Spec, func() { s.enqueueJob(job, "schedule") })
+		if err != nil {
+			return fmt.Errorf("Invalid cron expression '%s' for job %s: %v", job.Spec, job.Name, err)
+		}
 	}
 
-	// Start scheduler
+	s.cfg = cfg
+	s.cron = c
+	s.jobs = jobs
 	c.Start()
+	return nil
+}
+
+// reload re-reads the config file and, if it is valid, reschedules with the
+// new settings once any running job has finished. An invalid file is rejected
+// and the current configuration is kept.
+func (s *scheduler) reload() {
+	s.jobMu.Lock()
+	defer s.jobMu.Unlock()
 
-	// Calculate and display next run time
-	entries := c.Entries()
-	if len(entries) > 0 {
-		nextRun := entries[0].Next
-		pterm.Info.Printf("Scheduler started. Next sync scheduled for: %s\n",
-			nextRun.Format("2006-01-02 15:04:05 MST"))
-		pterm.Info.Printf("Schedule: %s (Timezone: %s)\n",
-			cfg.Schedule.CronExpression, cfg.Schedule.Timezone)
+	loaded, err := config.Reload()
+	if err != nil {
+		slog.Error("Config reload rejected, keeping previous configuration", "err", err)
+		return
 	}
+	newCfg := loaded
+	applyFlagOverrides(s.cmd, &newCfg)
 
-	// Setup signal handling
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Run initial sync if requested
-	if runInitial {
-		pterm.Info.Println("Running initial sync...")
-		runSyncJobs(cfg)
+	changes := config.Diff(s.cfg, newCfg)
+	if len(changes) == 0 {
+		config.Apply(loaded)
+		slog.Info("Configuration reloaded, nothing changed")
+		return
+	}
+
+	oldCfg := s.cfg
+	s.cron.Stop()
+	if err := s.start(newCfg); err != nil {
+		slog.Error("Config reload rejected, keeping previous configuration and schedule", "err", err)
+		s.start(oldCfg)
+		return
+	}
+	config.Apply(loaded)
+	if err := setupLogging(newCfg); err != nil {
+		slog.Error("Could not apply the new log settings", "err", err)
 	}
+	slog.Info("Configuration reloaded", "changes", strings.Join(changes, "; "))
 
-	// Wait for interrupt signal
-	<-sigChan
-	pterm.Warning.Println("\nReceived interrupt signal. Shutting down scheduler...")
-	c.Stop()
-	pterm.Success.Println("Scheduler stopped gracefully.")
+	if newCfg.Cache.Enabled {
+		Load_cache(newCfg)
+	}
+	if s.srv != nil {
+		s.srv.SetToken(newCfg.Server.Token)
+		if newCfg.Server.Listen != oldCfg.Server.Listen {
+			slog.Warn("Server.Listen changes take effect after a restart")
+		}
+	}
+	if newCfg.Events.Enabled != oldCfg.Events.Enabled {
+		slog.Warn("Events.Enabled changes take effect after a restart")
+	}
+	if newCfg.Mqtt != oldCfg.Mqtt {
+		slog.Warn("Mqtt changes take effect after a restart")
+	}
+	if !newCfg.Schedule.Enabled {
+		slog.Warn("Scheduling is disabled in the new configuration. No jobs will run until it is re-enabled.")
+		return
+	}
+	s.printNextRun("Rescheduled.")
 }
 
-func runSyncJobs(cfg config.Config) {
+// nextRun returns the earliest next run of the scheduled jobs.
+func (s *scheduler) nextRun() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next time.Time
+	for _, entry := range s.cron.Entries() {
+		if next.IsZero() || entry.Next.Before(next) {
+			next = entry.Next
+		}
+	}
+	return next
+}
+
+// printNextRun displays the next run time of the scheduled jobs. The caller
+// must hold s.mu or be the only goroutine using s.
+func (s *scheduler) printNextRun(status string) {
+	fullSync := false
+	for _, job := range s.jobs {
+		// Entries() is sorted by next run, so look each job up by its own
+		// entry
+		next := s.cron.Entry(job.entry).Next.Format("2006-01-02 15:04:05 MST")
+		if job.Name == "full-sync" {
+			fullSync = true
+			slog.Info("Incremental sync enabled. Next full sync scheduled", "next_full_sync", next)
+			continue
+		}
+		slog.Info(status+" Next sync scheduled", "next_run", next,
+			"schedule", job.Spec, "timezone", s.cfg.Schedule.Timezone)
+	}
+	if !fullSync && s.cfg.Schedule.Incremental {
+		slog.Info("Incremental sync enabled")
+	}
+}
+
+// runSyncJobs runs one scheduled sync. With incremental sync enabled only the
+// subtitles in Bazarr's history since the last run are synced, unless full is
+// set or no previous run has been recorded.
+func runSyncJobs(run *syncRun, cfg config.Config, full bool) {
 	startTime := time.Now()
 	fmt.Printf("\n%s Starting scheduled sync job\n",
 		startTime.Format("2006-01-02 15:04:05"))
 	fmt.Println(strings.Repeat("=", 60))
-
-	// Create channels for progress tracking
-	progressChan := make(chan int, 1)
-	doneChan := make(chan bool)
-
-	// Track progress
-	go func() {
-		for {
-			select {
-			case <-progressChan:
-				// Progress update handled in sync functions
-			case <-doneChan:
-				return
-			}
-		}
-	}()
+	pingHeartbeats(cfg, run.Job, notify.PingStart, "")
 
 	// Load cache if enabled
 	if cfg.Cache.Enabled {
 		Load_cache(cfg)
 	}
 
-	// Run sync jobs based on configuration
-	if cfg.Schedule.SyncShows {
-		fmt.Println("\n📺 Syncing TV shows...")
-		sync_shows(cfg, progressChan)
+	var st state.State
+	if cfg.Schedule.Incremental {
+		var err error
+		st, err = state.Load(cfg.StateFile)
+		if err != nil {
+			slog.Error("Could not read state file", "path", cfg.StateFile, "err", err)
+		}
+		if !full && st.LastRun.IsZero() {
+			fmt.Println("No previous run recorded, running a full sync first.")
+			full = true
+		}
 	}
 
-	if cfg.Schedule.SyncMovies {
-		fmt.Println("\n🎬 Syncing movies...")
-		sync_movies(cfg, progressChan)
+	var parts []syncPart
+	if cfg.Schedule.Incremental && !full {
+		fmt.Println("\n🔎 Syncing new subtitles from Bazarr's history...")
+		parts = append(parts, historyPart(cfg, st.LastRun))
+	} else {
+		// Run sync jobs based on configuration
+		if cfg.Schedule.SyncShows {
+			parts = append(parts, announcedPart("\n📺 Syncing TV shows...", showsPart(cfg, allSelection)))
+		}
+		if cfg.Schedule.SyncMovies {
+			parts = append(parts, announcedPart("\n🎬 Syncing movies...", moviesPart(cfg, allSelection)))
+		}
 	}
 
-	close(doneChan)
+	run.execute(parts)
+	signal, summary := heartbeatResult(run)
+	pingHeartbeats(cfg, run.Job, signal, summary)
+
+	if cfg.Schedule.Incremental && !run.Failed() && !run.Cancelled() {
+		st.LastRun = startTime
+		if full {
+			st.LastFullRun = startTime
+		}
+		saveState(cfg, st)
+	}
 
 	duration := time.Since(startTime)
 	fmt.Println(strings.Repeat("=", 60))
 	fmt.Printf("✅ Sync job completed in %s\n", duration.Round(time.Second))
-
-	// If scheduled, show next run time
-	if cfg.Schedule.Enabled {
-		nextRun := calculateNextRun(cfg.Schedule.CronExpression, cfg.Schedule.Timezone)
-		if !nextRun.IsZero() {
-			fmt.Printf("⏰ Next sync scheduled for: %s\n\n",
-				nextRun.Format("2006-01-02 15:04:05 MST"))
-		}
-	}
 }
 
-func calculateNextRun(cronExpr string, timezone string) time.Time {
-	location, err := time.LoadLocation(timezone)
-	if err != nil {
-		location = time.UTC
+// announcedPart prints a heading before the part starts.
+func announcedPart(heading string, part syncPart) syncPart {
+	inner := part.Sync
+	part.Sync = func(run *syncRun) error {
+		fmt.Println(heading)
+		return inner(run)
 	}
+	return part
+}
 
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	schedule, err := parser.Parse(cronExpr)
-	if err != nil {
-		return time.Time{}
+func saveState(cfg config.Config, st state.State) {
+	if err := state.Save(cfg.StateFile, st); err != nil {
+		slog.Error("Could not write state file", "path", cfg.StateFile, "err", err)
 	}
-
-	return schedule.Next(time.Now().In(location))
 }
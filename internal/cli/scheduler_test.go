@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"testing"
+)
+
+// Movies and shows used to share one progress channel, so the second pass
+// sent on a channel the first had closed and panicked.
+func TestRunSyncJobsMoviesAndShows(t *testing.T) {
+	fake := newFakeBazarr(t)
+	cfg := fake.config()
+	cfg.Schedule.SyncMovies = true
+	cfg.Schedule.SyncShows = true
+
+	run := newRun("schedule", "sync")
+	runSyncJobs(run, cfg, true)
+
+	if len(run.Parts) != 2 || run.Parts[0].Name != "shows" || run.Parts[1].Name != "movies" {
+		t.Fatalf("parts = %+v, want shows then movies", run.Parts)
+	}
+	for _, part := range run.Parts {
+		if part.Err != nil {
+			t.Errorf("part %s failed: %v", part.Name, part.Err)
+		}
+	}
+
+	want := syncSummary{Success: 2, AlreadySynced: 1, Skipped: 1, Failed: 1}
+	if run.Summary != want {
+		t.Errorf("summary = %+v, want %+v", run.Summary, want)
+	}
+	if shows := (syncSummary{Success: 1, Failed: 1}); run.Parts[0].Summary != shows {
+		t.Errorf("shows summary = %+v, want %+v", run.Parts[0].Summary, shows)
+	}
+	if movies := (syncSummary{Success: 1, AlreadySynced: 1, Skipped: 1}); run.Parts[1].Summary != movies {
+		t.Errorf("movies summary = %+v, want %+v", run.Parts[1].Summary, movies)
+	}
+
+	// The failed subtitle is retried once, the others synced once
+	want2 := map[string]int{
+		"/movies/inception.en.srt":         1,
+		"/movies/inception.already.de.srt": 1,
+		"/tv/dark/s01e01.en.srt":           1,
+		"/tv/dark/s01e02.fail.en.srt":      2,
+	}
+	requests := fake.syncRequests()
+	for path, n := range want2 {
+		if requests[path] != n {
+			t.Errorf("%s synced %d times, want %d", path, requests[path], n)
+		}
+	}
+	if len(requests) != len(want2) {
+		t.Errorf("synced %v, want %v", requests, want2)
+	}
+}
+
+// A part that fails does not keep the other from running.
+func TestRunSyncJobsIsolatesFailedPart(t *testing.T) {
+	fake := newFakeBazarr(t)
+	fake.failSeries = true
+	cfg := fake.config()
+	cfg.Schedule.SyncMovies = true
+	cfg.Schedule.SyncShows = true
+
+	run := newRun("schedule", "sync")
+	runSyncJobs(run, cfg, true)
+
+	if len(run.Parts) != 2 {
+		t.Fatalf("parts = %+v, want shows and movies", run.Parts)
+	}
+	if run.Parts[0].Err == nil {
+		t.Error("shows part succeeded, want the series query error")
+	}
+	if run.Parts[1].Err != nil {
+		t.Errorf("movies part failed: %v", run.Parts[1].Err)
+	}
+	if !run.Failed() {
+		t.Error("run not failed")
+	}
+
+	want := syncSummary{Success: 1, AlreadySynced: 1, Skipped: 1}
+	if run.Summary != want {
+		t.Errorf("summary = %+v, want %+v", run.Summary, want)
+	}
+}
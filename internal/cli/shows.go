@@ -2,7 +2,8 @@ package cli
 
 import (
 	"fmt"
-	"os"
+	"log/slog"
+	"sort"
 	"strings"
 	"time"
 
@@ -20,7 +21,8 @@ var showsCmd = &cobra.Command{
 	Short:   "Sync subtitles to the audio track of TV shows",
 	Example: `  bazarr-sync sync shows
   bazarr-sync sync shows --list
-  bazarr-sync sync shows --sonarr-id 123,456`,
+  bazarr-sync sync shows --sonarr-id 123,456
+  bazarr-sync sync shows --since 2026-09-01 --until 2026-09-15`,
 	Long: `By default, Bazarr will try to sync the sub to the audio track:0 of the media. 
 This can fail due to many reasons mainly due to failure of bazarr to extract audio info. This is unfortunately out of my hands.
 The script by default will try to not use the golden section search method and will try to fix framerate issues. This can be changed using the flags.`,
@@ -28,33 +30,40 @@ The script by default will try to not use the golden section search method and w
 		cfg := config.GetConfig()
 
 		// Override config with command line flags
-		if cmd.Flags().Changed("golden-section") {
-			cfg.SyncOptions.GoldenSection = gss
-		}
-		if cmd.Flags().Changed("no-framerate-fix") {
-			cfg.SyncOptions.NoFramerateFix = no_framerate_fix
-		}
-		if cmd.Flags().Changed("use-cache") {
-			cfg.Cache.Enabled = use_cache
-		}
+		applyFlagOverrides(cmd, &cfg)
 		if cmd.Flags().Changed("verbose") {
 			verbose = true
 		}
 
+		checkConfig(cfg)
+
 		if cfg.Cache.Enabled {
 			Load_cache(cfg)
 		}
 
-		bazarr.HealthCheck(cfg)
+		if err := bazarr.HealthCheck(cfg); err != nil {
+			exit(errorExitCode(err))
+		}
 
+		if to_list && len(sonarrid) > 0 {
+			list_episodes(cfg, sonarrid)
+			return
+		}
 		if to_list {
 			list_shows(cfg)
 			return
 		}
 
-		runWithSignalHandler(func(c chan int) {
-			sync_shows(cfg, c)
-		})
+		filter, err := newDownloadFilter(cfg, false)
+		if err != nil {
+			slog.Error("Invalid filter", "err", err)
+			exit(errorExitCode(err))
+		}
+
+		sel := selection{Ids: sonarrid, ContinueFrom: showsContinueFrom, Filter: filter}
+		run := newRun("manual", "")
+		runWithSignalHandler(run, "episode", []syncPart{showsPart(cfg, sel)})
+		exitForRun(run)
 	},
 }
 
@@ -63,13 +72,14 @@ func init() {
 	showsCmd.Flags().IntSliceVar(&sonarrid, "sonarr-id", []int{}, "Specify a list of sonarr Ids to sync. Use --list to view your shows with respective sonarr id.")
 	showsCmd.Flags().IntVar(&showsContinueFrom, "continue-from", -1, "Continue with the given Sonarr episode ID.")
 	showsCmd.Flags().BoolVar(&verbose, "verbose", false, "Show detailed error messages")
+	showsCmd.Flags().StringVar(&syncSince, "since", "", "Only sync subtitles Bazarr downloaded since a duration ago (14d, 36h) or a date (2026-09-01).")
+	showsCmd.Flags().StringVar(&syncUntil, "until", "", "Only sync subtitles Bazarr downloaded until a duration ago or a date.")
 }
 
-func sync_shows(cfg config.Config, c chan int) {
+func sync_shows(run *syncRun, cfg config.Config, sel selection) error {
 	shows, err := bazarr.QuerySeries(cfg)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Query Error: Could not query series")
-		return
+		return fmt.Errorf("could not query series: %w", err)
 	}
 
 	totalShows := len(shows.Data)
@@ -77,187 +87,59 @@ func sync_shows(cfg config.Config, c chan int) {
 	fmt.Println("Starting sync process...")
 	fmt.Println(strings.Repeat("-", 60))
 
-	skipForward := showsContinueFrom != -1
-	successCount := 0
-	skipCount := 0
-	failCount := 0
-	alreadySyncedCount := 0
-
-	// Spinner characters
-	spinners := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	skipForward := sel.ContinueFrom != -1
 
-shows:
 	for i, show := range shows.Data {
-		if len(sonarrid) > 0 {
-			found := false
-			for _, id := range sonarrid {
-				if id == show.SonarrSeriesId {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue shows
-			}
+		if !sel.includes(show.SonarrSeriesId) || !sel.Filter.allowsId(show.SonarrSeriesId) {
+			continue
 		}
 
+		run.item("series", show.SonarrSeriesId, show.Title, i+1, totalShows)
+
 		episodes, err := bazarr.QueryEpisodes(cfg, show.SonarrSeriesId)
 		if err != nil {
-			fmt.Printf("[%d/%d] ERROR: %s - Could not query episodes\n", i+1, totalShows, show.Title)
+			run.logger().Error("Could not query episodes", "media_id", show.SonarrSeriesId, "title", show.Title, "err", err)
 			continue
 		}
 
-		if len(episodes.Data) == 0 {
-			fmt.Printf("[%d/%d] NO EPISODES: %s\n", i+1, totalShows, show.Title)
-			continue
-		}
-
-		fmt.Printf("[%d/%d] PROCESSING: %s (%d episodes)\n", i+1, totalShows, show.Title, len(episodes.Data))
-
 		for _, episode := range episodes.Data {
 			for _, subtitle := range episode.Subtitles {
+				if !sel.Filter.allowsPath(subtitle.Path) {
+					continue
+				}
+				if err := run.stopped(); err != nil {
+					return err
+				}
+
+				ref := subtitleRef{Kind: "episode", Id: episode.SonarrEpisodeId, Title: show.Title + " - " + episode.Title, Media: show.Title,
+					Language: subtitle.Code2, Path: subtitle.Path}
+
 				if skipForward {
-					if episode.SonarrEpisodeId == showsContinueFrom {
+					if episode.SonarrEpisodeId == sel.ContinueFrom {
 						skipForward = false
 					} else {
-						skipCount++
+						run.skip(ref, "continue mode")
 						continue
 					}
 				}
 
-				c <- episode.SonarrEpisodeId
-
 				if subtitle.Path == "" || subtitle.File_size == 0 {
-					fmt.Printf("  └─ SKIP [%s - %s]: Embedded or missing\n", episode.Title, subtitle.Code2)
-					skipCount++
+					run.skip(ref, "embedded or missing")
 					continue
 				}
 
 				if cfg.Cache.Enabled {
 					_, exists := shows_cache[subtitle.Path]
 					if exists {
-						fmt.Printf("  └─ CACHED [%s - %s]: Already synced\n", episode.Title, subtitle.Code2)
-						skipCount++
+						run.skip(ref, "cached")
 						continue
 					}
 				}
 
-				params := bazarr.GetSyncParams("episode", episode.SonarrEpisodeId, subtitle)
-				if cfg.SyncOptions.GoldenSection {
-					params.Gss = "True"
-				}
-				if cfg.SyncOptions.NoFramerateFix {
-					params.No_framerate_fix = "True"
-				}
-
-				// Start sync with spinner
-				fmt.Printf("  └─ SYNCING [%s - %s]: ", episode.Title, subtitle.Code2)
-
-				// Start sync in background
-				syncDone := make(chan struct {
-					success bool
-					message string
-				})
-
-				go func() {
-					ok, msg := bazarr.Sync(cfg, params)
-					syncDone <- struct {
-						success bool
-						message string
-					}{ok, msg}
-				}()
-
-				// Show spinner while waiting
-				spinnerIndex := 0
-				ticker := time.NewTicker(100 * time.Millisecond)
-				defer ticker.Stop()
-
-				var result struct {
-					success bool
-					message string
-				}
-
-			spinnerLoop:
-				for {
-					select {
-					case result = <-syncDone:
-						break spinnerLoop
-					case <-ticker.C:
-						fmt.Printf("\r  └─ SYNCING [%s - %s]: %s ", episode.Title, subtitle.Code2, spinners[spinnerIndex])
-						spinnerIndex = (spinnerIndex + 1) % len(spinners)
-					}
-				}
-
-				// Clear spinner and show result
-				fmt.Printf("\r  └─ SYNCING [%s - %s]: ", episode.Title, subtitle.Code2)
-
-				if result.success {
-					fmt.Printf("✓ Success                    \n")
+				outcome := syncSubtitle(run, cfg, ref)
+				if outcome != outcomeFailed {
+					// Already in sync is cached too, so we don't try again
 					Write_shows_cache(cfg, subtitle.Path)
-					successCount++
-				} else {
-					// Check if it's already synced
-					if strings.Contains(strings.ToLower(result.message), "already") ||
-						strings.Contains(strings.ToLower(result.message), "sync") ||
-						strings.Contains(result.message, "304") ||
-						strings.Contains(result.message, "409") {
-						fmt.Printf("✓ Already in sync            \n")
-						Write_shows_cache(cfg, subtitle.Path) // Cache it so we don't try again
-						alreadySyncedCount++
-					} else {
-						// Retry once for real failures
-						if verbose {
-							fmt.Printf("✗ Failed (%s), retrying...\n  └─ RETRYING [%s - %s]: ", result.message, episode.Title, subtitle.Code2)
-						} else {
-							fmt.Printf("✗ Failed, retrying...        \n  └─ RETRYING [%s - %s]: ", episode.Title, subtitle.Code2)
-						}
-
-						// Retry with spinner
-						go func() {
-							time.Sleep(2 * time.Second)
-							ok, msg := bazarr.Sync(cfg, params)
-							syncDone <- struct {
-								success bool
-								message string
-							}{ok, msg}
-						}()
-
-						// Show spinner for retry
-						spinnerIndex = 0
-						ticker = time.NewTicker(100 * time.Millisecond)
-					retrySpinner:
-						for {
-							select {
-							case result = <-syncDone:
-								ticker.Stop()
-								break retrySpinner
-							case <-ticker.C:
-								fmt.Printf("\r  └─ RETRYING [%s - %s]: %s ", episode.Title, subtitle.Code2, spinners[spinnerIndex])
-								spinnerIndex = (spinnerIndex + 1) % len(spinners)
-							}
-						}
-
-						// Clear spinner and show retry result
-						fmt.Printf("\r  └─ RETRYING [%s - %s]: ", episode.Title, subtitle.Code2)
-
-						if result.success {
-							fmt.Printf("✓ Success                    \n")
-							Write_shows_cache(cfg, subtitle.Path)
-							successCount++
-						} else if strings.Contains(strings.ToLower(result.message), "already") ||
-							strings.Contains(strings.ToLower(result.message), "sync") {
-							fmt.Printf("✓ Already in sync            \n")
-							Write_shows_cache(cfg, subtitle.Path)
-							alreadySyncedCount++
-						} else {
-							if verbose {
-								fmt.Printf("✗ Failed: %s\n", result.message)
-							} else {
-								fmt.Printf("✗ Failed                     \n")
-							}
-							failCount++
-						}
-					}
 				}
 
 				// Add delay between syncs to avoid overwhelming Bazarr
@@ -266,33 +148,78 @@ shows:
 		}
 	}
 
-	fmt.Println(strings.Repeat("-", 60))
-	fmt.Printf("Sync completed:\n")
-	fmt.Printf("  ✅ %d newly synced\n", successCount)
-	fmt.Printf("  ✓  %d already in sync\n", alreadySyncedCount)
-	fmt.Printf("  ⏭️  %d skipped (cached/embedded)\n", skipCount)
-	fmt.Printf("  ❌ %d failed\n", failCount)
-
-	if failCount > 0 && !verbose {
-		fmt.Println("\n💡 Tip: Run with --verbose to see detailed error messages")
-	}
-
-	close(c)
+	return nil
 }
 
 func list_shows(cfg config.Config) {
 	shows, err := bazarr.QuerySeries(cfg)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Query Error: Could not query shows")
-		return
+		slog.Error("Could not query shows", "err", err)
+		exit(errorExitCode(err))
 	}
 
-	fmt.Printf("%-60s %s\n", "Title", "SonarrSeriesId")
-	fmt.Println(strings.Repeat("-", 70))
+	states := loadSyncStates(cfg)
+	var items []listedItem
+	for i, show := range shows.Data {
+		item := listedItem{Title: show.Title, SonarrSeriesId: show.SonarrSeriesId}
+		if listOpts.subtitles() && fuzzyMatch(listOpts.search, show.Title) {
+			progressf("[%d/%d] %s\n", i+1, len(shows.Data), show.Title)
+			episodes, err := bazarr.QueryEpisodes(cfg, show.SonarrSeriesId)
+			if err != nil {
+				slog.Error("Could not query episodes", "series", show.Title, "err", err)
+				exit(errorExitCode(err))
+			}
+			for _, episode := range episodes.Data {
+				item.addSubtitles(episode.Subtitles, states)
+			}
+		}
+		item.setColumns(listOpts, show.Monitored, show.ImdbId)
+		items = append(items, item)
+	}
+	printList(filterList(items, listOpts), listOpts, "sonarr_series_id", "shows")
+}
 
+// list_episodes lists the episodes of the shows given with --sonarr-id, in
+// season and episode order unless --sort is given.
+func list_episodes(cfg config.Config, seriesIds []int) {
+	shows, err := bazarr.QuerySeries(cfg)
+	if err != nil {
+		slog.Error("Could not query shows", "err", err)
+		exit(errorExitCode(err))
+	}
+	titles := make(map[int]string)
 	for _, show := range shows.Data {
-		fmt.Printf("%-60s %d\n", show.Title, show.SonarrSeriesId)
+		titles[show.SonarrSeriesId] = show.Title
 	}
 
-	fmt.Printf("\nTotal: %d shows\n", len(shows.Data))
+	states := loadSyncStates(cfg)
+	var items []listedItem
+	for _, seriesId := range seriesIds {
+		if _, found := titles[seriesId]; !found {
+			slog.Warn("No show with this Sonarr series ID", "sonarr_series_id", seriesId)
+			continue
+		}
+		episodes, err := bazarr.QueryEpisodes(cfg, seriesId)
+		if err != nil {
+			slog.Error("Could not query episodes", "sonarr_series_id", seriesId, "err", err)
+			exit(errorExitCode(err))
+		}
+
+		var showItems []listedItem
+		for _, episode := range episodes.Data {
+			item := listedItem{Title: episode.Title, Series: titles[seriesId], SonarrSeriesId: seriesId, SonarrEpisodeId: episode.SonarrEpisodeId,
+				Season: &episode.Season, Episode: &episode.Episode}
+			item.addSubtitles(episode.Subtitles, states)
+			item.setColumns(listOpts, episode.Monitored, "")
+			showItems = append(showItems, item)
+		}
+		sort.SliceStable(showItems, func(a, b int) bool {
+			if *showItems[a].Season != *showItems[b].Season {
+				return *showItems[a].Season < *showItems[b].Season
+			}
+			return *showItems[a].Episode < *showItems[b].Episode
+		})
+		items = append(items, showItems...)
+	}
+	printList(filterList(items, listOpts), listOpts, "sonarr_episode_id", "episodes")
 }
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+)
+
+var syncSince string
+var syncUntil string
+
+// Accepted --since/--until date layouts, interpreted in local time
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+}
+
+// parseTimeFlag turns a --since/--until value into a point in time. It accepts
+// a duration back from now ("36h", "14d", "2w") or a date ("2026-09-01",
+// "2026-09-01 18:00", RFC 3339).
+func parseTimeFlag(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	// Days and weeks are not understood by time.ParseDuration
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, found := strings.CutSuffix(value, suffix); found {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				break
+			}
+			return now.Add(-time.Duration(count) * unit), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%q is neither a duration (14d, 36h) nor a date (2026-09-01)", value)
+}
+
+// downloadFilter restricts a sync to the subtitles Bazarr downloaded in the
+// window given by --since and --until.
+type downloadFilter struct {
+	paths map[string]bool
+	// Radarr movie IDs or Sonarr series IDs with a subtitle in the window
+	ids map[int]bool
+	// The window; either end may be zero
+	since time.Time
+	until time.Time
+}
+
+// newDownloadFilter resolves --since/--until against Bazarr's history for
+// movies or, when movies is false, episodes. It returns nil when neither
+// flag is set.
+func newDownloadFilter(cfg config.Config, movies bool) (*downloadFilter, error) {
+	if syncSince == "" && syncUntil == "" {
+		return nil, nil
+	}
+
+	now := time.Now()
+	var since, until time.Time
+	var err error
+	if syncSince != "" {
+		if since, err = parseTimeFlag(syncSince, now); err != nil {
+			return nil, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if syncUntil != "" {
+		if until, err = parseTimeFlag(syncUntil, now); err != nil {
+			return nil, fmt.Errorf("invalid --until: %w", err)
+		}
+		if until.Before(since) {
+			return nil, fmt.Errorf("--until %s is before --since %s", until.Format(time.DateTime), since.Format(time.DateTime))
+		}
+	}
+
+	items, err := queryHistoryItems(cfg, since, until, movies, !movies)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &downloadFilter{paths: make(map[string]bool), ids: make(map[int]bool), since: since, until: until}
+	for _, item := range items {
+		filter.paths[item.subtitle.Path] = true
+		if item.kind == "episode" {
+			filter.ids[item.seriesId] = true
+		} else {
+			filter.ids[item.id] = true
+		}
+	}
+
+	window := "since " + since.Format(time.DateTime)
+	if since.IsZero() {
+		window = "until " + until.Format(time.DateTime)
+	} else if !until.IsZero() {
+		window = fmt.Sprintf("between %s and %s", since.Format(time.DateTime), until.Format(time.DateTime))
+	}
+	fmt.Printf("Limiting sync to %d subtitles downloaded %s.\n", len(filter.paths), window)
+
+	return filter, nil
+}
+
+// allowsId reports whether a movie or series has a subtitle in the window.
+func (f *downloadFilter) allowsId(id int) bool {
+	return f == nil || f.ids[id]
+}
+
+// allowsPath reports whether a subtitle file was downloaded in the window.
+func (f *downloadFilter) allowsPath(path string) bool {
+	return f == nil || f.paths[path]
+}
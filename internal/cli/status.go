@@ -0,0 +1,356 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/regix1/bazarr-sync/internal/bazarr"
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var statusBy string
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which media have subtitles and how many of them are synced",
+	Long: `Queries all movies, series and episodes in Bazarr and reports how many have external
+subtitles, per language, and how many have only embedded subtitles or none at all.
+
+External subtitles are counted as synced, failed or never synced by their latest outcome in
+the run history; subtitles that are only in the cache count as synced. Failed subtitles that
+failed in History.QuarantineAfter runs in a row are counted as quarantined instead.`,
+	Example: `  bazarr-sync status
+  bazarr-sync status --by show
+  bazarr-sync status --by season --output json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.GetConfig()
+		checkConfig(cfg)
+		if statusBy != "" && statusBy != "show" && statusBy != "season" {
+			slog.Error("--by must be show or season", "by", statusBy)
+			exit(exitError)
+		}
+
+		if err := bazarr.HealthCheck(cfg); err != nil {
+			exit(errorExitCode(err))
+		}
+		Load_cache(cfg)
+
+		status, err := queryLibraryStatus(cfg, loadSyncStates(cfg))
+		if err != nil {
+			slog.Error("Could not query the library", "err", err)
+			exit(errorExitCode(err))
+		}
+
+		if structuredOutput() {
+			writeStructured(status)
+			return
+		}
+		status.print(statusBy)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringVar(&statusBy, "by", "", "Also break the episodes down by show or season")
+}
+
+// Sync states of an external subtitle
+const (
+	stateSynced      = "synced"
+	stateFailed      = "failed"
+	stateQuarantined = "quarantined" // failed in History.QuarantineAfter runs in a row
+	stateNeverSynced = "never_synced"
+)
+
+// syncStates knows which subtitles were synced, from the run history and the
+// cache.
+type syncStates struct {
+	latest      map[string]history.Subtitle
+	quarantined map[string]bool
+	cached      map[string]bool
+}
+
+func loadSyncStates(cfg config.Config) syncStates {
+	states := syncStates{quarantined: make(map[string]bool), cached: make(map[string]bool)}
+	if cfg.History.Dir != "" {
+		runs, err := history.Open(cfg.History.Dir).Runs(0)
+		if err != nil {
+			slog.Warn("Could not read the run history", "dir", cfg.History.Dir, "err", err)
+		}
+		states.latest = history.Latest(runs)
+		for _, f := range history.Failures(runs) {
+			if f.Quarantined(cfg.History.QuarantineAfter) {
+				states.quarantined[f.Path] = true
+			}
+		}
+	}
+
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	for path := range movies_cache {
+		states.cached[path] = true
+	}
+	for path := range shows_cache {
+		states.cached[path] = true
+	}
+	return states
+}
+
+func (s syncStates) of(path string) string {
+	if sub, found := s.latest[path]; found {
+		if sub.Outcome == outcomeFailed.String() {
+			if s.quarantined[path] {
+				return stateQuarantined
+			}
+			return stateFailed
+		}
+		return stateSynced
+	}
+	if s.cached[path] {
+		return stateSynced
+	}
+	return stateNeverSynced
+}
+
+// coverage counts the movies or episodes with subtitles and the sync state
+// of their external subtitles.
+type coverage struct {
+	Items        int `json:"items"`
+	External     int `json:"with_external"`
+	EmbeddedOnly int `json:"embedded_only"`
+	None         int `json:"none"`
+	// External subtitles
+	Subtitles   int                          `json:"subtitles"`
+	Synced      int                          `json:"synced"`
+	NeverSynced int                          `json:"never_synced"`
+	Failed      int                          `json:"failed"`
+	Quarantined int                          `json:"quarantined"`
+	Languages   map[string]*languageCoverage `json:"languages"`
+}
+
+// languageCoverage counts the items with an external subtitle in a language,
+// and those subtitles by sync state.
+type languageCoverage struct {
+	Items       int `json:"items"`
+	Subtitles   int `json:"subtitles"`
+	Synced      int `json:"synced"`
+	NeverSynced int `json:"never_synced"`
+	Failed      int `json:"failed"`
+	Quarantined int `json:"quarantined"`
+}
+
+func newCoverage() coverage {
+	return coverage{Languages: make(map[string]*languageCoverage)}
+}
+
+// add counts a movie or episode with its subtitles.
+func (c *coverage) add(subtitles []bazarr.Subtitle, states syncStates) {
+	c.Items++
+	external := 0
+	counted := make(map[string]bool)
+	for _, sub := range subtitles {
+		if sub.Path == "" || sub.File_size == 0 {
+			continue
+		}
+		external++
+		lang, found := c.Languages[sub.Code2]
+		if !found {
+			lang = &languageCoverage{}
+			c.Languages[sub.Code2] = lang
+		}
+		if !counted[sub.Code2] {
+			counted[sub.Code2] = true
+			lang.Items++
+		}
+
+		c.Subtitles++
+		lang.Subtitles++
+		switch states.of(sub.Path) {
+		case stateSynced:
+			c.Synced++
+			lang.Synced++
+		case stateFailed:
+			c.Failed++
+			lang.Failed++
+		case stateQuarantined:
+			c.Quarantined++
+			lang.Quarantined++
+		default:
+			c.NeverSynced++
+			lang.NeverSynced++
+		}
+	}
+
+	switch {
+	case external > 0:
+		c.External++
+	case len(subtitles) > 0:
+		c.EmbeddedOnly++
+	default:
+		c.None++
+	}
+}
+
+// libraryStatus is the coverage of the whole library, printed by status.
+type libraryStatus struct {
+	Movies   coverage     `json:"movies"`
+	Episodes coverage     `json:"episodes"`
+	Shows    []showStatus `json:"shows"`
+}
+
+type showStatus struct {
+	Title          string `json:"title"`
+	SonarrSeriesId int    `json:"sonarr_series_id"`
+	coverage
+	Seasons []seasonStatus `json:"seasons"`
+}
+
+type seasonStatus struct {
+	Season int `json:"season"`
+	coverage
+}
+
+func queryLibraryStatus(cfg config.Config, states syncStates) (libraryStatus, error) {
+	status := libraryStatus{Movies: newCoverage(), Episodes: newCoverage(), Shows: []showStatus{}}
+
+	movies, err := bazarr.QueryMovies(cfg)
+	if err != nil {
+		return status, fmt.Errorf("could not query movies: %w", err)
+	}
+	for _, movie := range movies.Data {
+		status.Movies.add(movie.Subtitles, states)
+	}
+
+	shows, err := bazarr.QuerySeries(cfg)
+	if err != nil {
+		return status, fmt.Errorf("could not query series: %w", err)
+	}
+	for i, show := range shows.Data {
+		progressf("[%d/%d] %s\n", i+1, len(shows.Data), show.Title)
+		episodes, err := bazarr.QueryEpisodes(cfg, show.SonarrSeriesId)
+		if err != nil {
+			return status, fmt.Errorf("could not query episodes of %s: %w", show.Title, err)
+		}
+
+		s := showStatus{Title: show.Title, SonarrSeriesId: show.SonarrSeriesId, coverage: newCoverage()}
+		seasons := make(map[int]*seasonStatus)
+		for _, episode := range episodes.Data {
+			season, found := seasons[episode.Season]
+			if !found {
+				season = &seasonStatus{Season: episode.Season, coverage: newCoverage()}
+				seasons[episode.Season] = season
+			}
+			status.Episodes.add(episode.Subtitles, states)
+			s.add(episode.Subtitles, states)
+			season.add(episode.Subtitles, states)
+		}
+		for _, season := range seasons {
+			s.Seasons = append(s.Seasons, *season)
+		}
+		sort.Slice(s.Seasons, func(i, j int) bool {
+			return s.Seasons[i].Season < s.Seasons[j].Season
+		})
+		status.Shows = append(status.Shows, s)
+	}
+	return status, nil
+}
+
+func (s libraryStatus) print(by string) {
+	fmt.Println()
+	printCoverage(fmt.Sprintf("Movies: %d", s.Movies.Items), s.Movies)
+	printCoverage(fmt.Sprintf("Episodes: %d in %d shows", s.Episodes.Items, len(s.Shows)), s.Episodes)
+	s.printLanguages()
+
+	switch by {
+	case "show":
+		fmt.Println()
+		printCoverageHeader("Show")
+		for _, show := range s.Shows {
+			printCoverageRow(show.Title, show.coverage)
+		}
+	case "season":
+		fmt.Println()
+		printCoverageHeader("Season")
+		for _, show := range s.Shows {
+			for _, season := range show.Seasons {
+				printCoverageRow(fmt.Sprintf("%s S%02d", show.Title, season.Season), season.coverage)
+			}
+		}
+	}
+}
+
+func printCoverage(heading string, c coverage) {
+	fmt.Println(heading)
+	fmt.Printf("  %-26s %6d%s\n", "with external subtitles", c.External, percent(c.External, c.Items))
+	fmt.Printf("  %-26s %6d%s\n", "only embedded subtitles", c.EmbeddedOnly, percent(c.EmbeddedOnly, c.Items))
+	fmt.Printf("  %-26s %6d%s\n", "no subtitles", c.None, percent(c.None, c.Items))
+	fmt.Printf("  %-26s %6d: %d synced, %d never synced, %d failed, %d quarantined\n", "external subtitles",
+		c.Subtitles, c.Synced, c.NeverSynced, c.Failed, c.Quarantined)
+	fmt.Println()
+}
+
+func percent(n int, total int) string {
+	if total == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%d%%)", n*100/total)
+}
+
+// printLanguages shows the movies and episodes with an external subtitle per
+// language, and the sync state of those subtitles.
+func (s libraryStatus) printLanguages() {
+	var codes []string
+	for code := range s.Movies.Languages {
+		codes = append(codes, code)
+	}
+	for code := range s.Episodes.Languages {
+		if s.Movies.Languages[code] == nil {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return
+	}
+	sort.Strings(codes)
+
+	fmt.Printf("%-10s %8s %9s %10s %8s %13s %7s %12s\n", "Language", "Movies", "Episodes", "Subtitles", "Synced", "Never synced",
+		"Failed", "Quarantined")
+	fmt.Println(strings.Repeat("-", 84))
+	for _, code := range codes {
+		var total languageCoverage
+		var movies, episodes int
+		for i, lang := range []*languageCoverage{s.Movies.Languages[code], s.Episodes.Languages[code]} {
+			if lang == nil {
+				continue
+			}
+			if i == 0 {
+				movies = lang.Items
+			} else {
+				episodes = lang.Items
+			}
+			total.Subtitles += lang.Subtitles
+			total.Synced += lang.Synced
+			total.NeverSynced += lang.NeverSynced
+			total.Failed += lang.Failed
+			total.Quarantined += lang.Quarantined
+		}
+		fmt.Printf("%-10s %8d %9d %10d %8d %13d %7d %12d\n", code, movies, episodes,
+			total.Subtitles, total.Synced, total.NeverSynced, total.Failed, total.Quarantined)
+	}
+}
+
+func printCoverageHeader(name string) {
+	fmt.Printf("%-40s %8s %8s %8s %6s %9s %7s %6s %6s %11s\n", name, "Episodes", "External", "Embedded", "None",
+		"Subtitles", "Synced", "Never", "Failed", "Quarantined")
+	fmt.Println(strings.Repeat("-", 119))
+}
+
+func printCoverageRow(name string, c coverage) {
+	fmt.Printf("%-40.40s %8d %8d %8d %6d %9d %7d %6d %6d %11d\n", name, c.Items, c.External, c.EmbeddedOnly, c.None,
+		c.Subtitles, c.Synced, c.NeverSynced, c.Failed, c.Quarantined)
+}
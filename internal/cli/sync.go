@@ -13,9 +13,16 @@ var syncCmd = &cobra.Command{
 Use 'movies' or 'shows' subcommands to specify what to sync.`,
 	Example: `  bazarr-sync sync movies
   bazarr-sync sync shows
-  bazarr-sync sync movies --list`,
+  bazarr-sync sync movies --list
+  bazarr-sync sync shows --list --columns languages,status --search "breaking"
+  bazarr-sync sync shows --report weekly.html`,
 }
 
 func init() {
 	rootCmd.AddCommand(syncCmd)
+	syncCmd.PersistentFlags().StringSliceVar(&listColumns, "columns", []string{}, "Extra --list columns: languages, subtitles, status, monitored, imdb or all")
+	syncCmd.PersistentFlags().StringVar(&listSort, "sort", "", "Sort --list by title, id, subtitles or status (default Bazarr's order)")
+	syncCmd.PersistentFlags().StringVar(&listSearch, "search", "", "Only --list titles matching this fuzzy search")
+	syncCmd.PersistentFlags().StringVar(&listFormat, "format", "", "--list format: table, json or csv (default table, json with --output json)")
+	syncCmd.PersistentFlags().StringVar(&reportFile, "report", "", "Write a report of the run to this .html, .md or .csv file")
 }
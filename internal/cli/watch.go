@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/bazarr"
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var watchInterval time.Duration
+var watchHeartbeat time.Duration
+
+var watchCmd = &cobra.Command{
+	Use:     "watch",
+	Aliases: []string{"w"},
+	Short:   "Keep running and sync subtitles as soon as Bazarr downloads them",
+	Example: `  bazarr-sync watch
+  bazarr-sync watch --interval 2m --heartbeat 30m`,
+	Long: `Polls Bazarr's history at a fixed interval and syncs every subtitle downloaded,
+upgraded or uploaded since the last poll.
+
+The time of the newest handled history entry is kept in the state file, so a restart
+picks up where the previous watch stopped. Connection failures are logged and retried
+on the next poll, so a restarting Bazarr does not stop the watch.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.GetConfig()
+
+		// Override config with command line flags
+		applyFlagOverrides(cmd, &cfg)
+		if cmd.Flags().Changed("interval") {
+			cfg.Watch.Interval = watchInterval
+		}
+		if cmd.Flags().Changed("heartbeat") {
+			cfg.Watch.HeartbeatInterval = watchHeartbeat
+		}
+		checkConfig(cfg)
+
+		if cfg.Cache.Enabled {
+			Load_cache(cfg)
+		}
+
+		// Bazarr may only be down for now; polling keeps trying
+		bazarr.HealthCheck(cfg)
+
+		runWatcher(cfg)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 5*time.Minute, "How often to poll Bazarr's history")
+	watchCmd.Flags().DurationVar(&watchHeartbeat, "heartbeat", time.Hour, "How often to log a status line, 0 to disable")
+	watchCmd.Flags().BoolVar(&verbose, "verbose", false, "Show detailed error messages")
+}
+
+// watcher polls Bazarr's history and syncs new subtitles as they appear.
+type watcher struct {
+	cfg   config.Config
+	state state.State
+
+	started     time.Time
+	polls       int
+	lastPoll    time.Time
+	pollFailing bool
+	summary     syncSummary
+
+	// SIGINT and SIGTERM, also watched while a batch syncs
+	signals chan os.Signal
+}
+
+func runWatcher(cfg config.Config) {
+	st, err := state.Load(cfg.StateFile)
+	if err != nil {
+		slog.Error("Could not read state file", "path", cfg.StateFile, "err", err)
+	}
+
+	w := &watcher{cfg: cfg, state: st, started: time.Now()}
+	if w.state.WatchMark.IsZero() {
+		// Nothing handled yet: start from now rather than syncing all of history
+		w.state.WatchMark = w.started
+		saveState(cfg, w.state)
+		slog.Info("No previous watch recorded. Watching for subtitles downloaded from now on.")
+	} else {
+		slog.Info("Resuming watch", "from", w.state.WatchMark.Format("2006-01-02 15:04:05 MST"))
+	}
+	slog.Info("Polling Bazarr", "every", cfg.Watch.Interval)
+
+	w.signals = make(chan os.Signal, 1)
+	signal.Notify(w.signals, syscall.SIGINT, syscall.SIGTERM)
+
+	poll := time.NewTicker(cfg.Watch.Interval)
+	defer poll.Stop()
+
+	// A nil channel never fires, which disables the heartbeat
+	var heartbeat <-chan time.Time
+	if cfg.Watch.HeartbeatInterval > 0 {
+		ticker := time.NewTicker(cfg.Watch.HeartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	if w.poll() {
+		w.printHeartbeat()
+		return
+	}
+	for {
+		select {
+		case <-poll.C:
+			if w.poll() {
+				w.printHeartbeat()
+				return
+			}
+		case <-heartbeat:
+			w.printHeartbeat()
+		case <-w.signals:
+			slog.Info("Received interrupt signal. Stopping watch")
+			w.printHeartbeat()
+			return
+		}
+	}
+}
+
+// poll syncs everything Bazarr recorded since the high-water mark and moves
+// the mark past it. Failed syncs are not retried by later polls. A signal
+// while syncing cancels the batch, leaves the mark where it was and makes
+// poll return true.
+func (w *watcher) poll() (interrupted bool) {
+	w.polls++
+	w.lastPoll = time.Now()
+
+	items, err := queryHistoryItems(w.cfg, w.state.WatchMark, time.Time{}, w.cfg.Watch.SyncMovies, w.cfg.Watch.SyncShows)
+	if err != nil {
+		if !w.pollFailing {
+			slog.Warn("Could not reach Bazarr. Retrying", "every", w.cfg.Watch.Interval, "err", err)
+		}
+		w.pollFailing = true
+		return false
+	}
+	if w.pollFailing {
+		slog.Info("Connection to Bazarr restored")
+		w.pollFailing = false
+	}
+	if len(items) == 0 {
+		return false
+	}
+
+	slog.Info("Found new subtitles", "subtitles", len(items))
+	run := newRun("watch", "")
+	done := make(chan struct{})
+	stopped := make(chan bool, 1)
+	go func() {
+		select {
+		case <-w.signals:
+			slog.Info("Received interrupt signal. Stopping watch after the current subtitle")
+			run.Cancel()
+			stopped <- true
+		case <-done:
+			stopped <- false
+		}
+	}()
+	run.execute([]syncPart{historyItemsPart(w.cfg, items)})
+	close(done)
+	w.summary.add(run.Summary)
+	if <-stopped {
+		return true
+	}
+
+	for _, item := range items {
+		if item.downloaded.After(w.state.WatchMark) {
+			w.state.WatchMark = item.downloaded
+		}
+	}
+	saveState(w.cfg, w.state)
+	return false
+}
+
+func (w *watcher) printHeartbeat() {
+	status := "ok"
+	if w.pollFailing {
+		status = "Bazarr unreachable"
+	}
+	attrs := []any{"watching_for", time.Since(w.started).Round(time.Second), "status", status, "polls", w.polls}
+	attrs = append(attrs, summaryAttrs(w.summary)...)
+	attrs = append(attrs, "last_poll", w.lastPoll.Format("15:04:05"), "watch_mark", w.state.WatchMark.Format("2006-01-02 15:04:05 MST"))
+	slog.Info("Watch status", attrs...)
+}
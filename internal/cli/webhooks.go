@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/bazarr"
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/server"
+)
+
+// Imported implements server.Controller.
+func (s *scheduler) Imported(ev server.ImportEvent) error {
+	if len(ev.Ids) == 0 {
+		return fmt.Errorf("%w: the %s payload names no media", server.ErrInvalidRequest, ev.Source)
+	}
+
+	s.mu.Lock()
+	cfg := s.cfg
+	s.mu.Unlock()
+
+	verb := "imported"
+	if ev.Upgrade {
+		verb = "upgraded"
+	}
+	source := map[string]string{"radarr": "Radarr", "sonarr": "Sonarr"}[ev.Source]
+	slog.Info(source+" "+verb+" media. Checking Bazarr for subtitles after the delay", "title", ev.Title, "media_ids", ev.Ids, "delay", cfg.Webhook.Delay)
+	go s.awaitSubtitles(cfg, ev)
+	return nil
+}
+
+// awaitSubtitles polls Bazarr until every imported movie or episode has an
+// external subtitle or the timeout passes, then queues a sync of the
+// subtitles found.
+func (s *scheduler) awaitSubtitles(cfg config.Config, ev server.ImportEvent) {
+	deadline := time.Now().Add(cfg.Webhook.Timeout)
+	time.Sleep(cfg.Webhook.Delay)
+
+	for {
+		items, complete, err := importedSubtitles(cfg, ev)
+		if err != nil {
+			slog.Warn("Could not check Bazarr for subtitles", "title", ev.Title, "err", err)
+		}
+
+		if complete || time.Now().Add(cfg.Webhook.PollInterval).After(deadline) {
+			if len(items) == 0 {
+				slog.Warn("No subtitles appeared in Bazarr in time", "title", ev.Title, "timeout", cfg.Webhook.Timeout)
+				return
+			}
+			slog.Info("Found subtitles. Queueing sync.", "title", ev.Title, "subtitles", len(items))
+			s.enqueue(&queuedRun{run: newRun("webhook", ""), items: items})
+			return
+		}
+		time.Sleep(cfg.Webhook.PollInterval)
+	}
+}
+
+// importedSubtitles returns the external subtitles Bazarr has for the
+// imported media, and whether every movie or episode has at least one.
+func importedSubtitles(cfg config.Config, ev server.ImportEvent) ([]historyItem, bool, error) {
+	var items []historyItem
+	complete := true
+	add := func(id int, media string, title string, subtitles []bazarr.Subtitle) {
+		found := false
+		for _, subtitle := range subtitles {
+			// Embedded subtitles cannot be synced
+			if subtitle.Path == "" || subtitle.File_size == 0 {
+				continue
+			}
+			found = true
+			// An upgraded video needs its subtitles synced again even if
+			// they were synced against the old file, so the cache is ignored
+			items = append(items, historyItem{kind: ev.Kind, id: id, seriesId: ev.SeriesId, title: title, media: media,
+				subtitle: subtitle, upgraded: ev.Upgrade})
+		}
+		if !found {
+			complete = false
+		}
+	}
+
+	if ev.Kind == "movie" {
+		movies, err := bazarr.QueryMoviesById(cfg, ev.Ids)
+		if err != nil {
+			return nil, false, err
+		}
+		// Bazarr may not know about a new movie yet
+		if len(movies.Data) < len(ev.Ids) {
+			complete = false
+		}
+		for _, movie := range movies.Data {
+			add(movie.RadarrId, movie.Title, movie.Title, movie.Subtitles)
+		}
+		return items, complete, nil
+	}
+
+	episodes, err := bazarr.QueryEpisodesById(cfg, ev.Ids)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(episodes.Data) < len(ev.Ids) {
+		complete = false
+	}
+	for _, episode := range episodes.Data {
+		add(episode.SonarrEpisodeId, ev.Title, fmt.Sprintf("%s - %s", ev.Title, episode.Title), episode.Subtitles)
+	}
+	return items, complete, nil
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
@@ -13,6 +14,10 @@ type HttpClient struct {
 
 var client *HttpClient
 
+// RequestObserver, when set, is called after every request to Bazarr with
+// the response status (0 when the request failed) and how long it took.
+var RequestObserver func(req *http.Request, status int, duration time.Duration)
+
 // Singleton
 func GetClient(token string) *HttpClient {
 	if client == nil {
@@ -33,13 +38,32 @@ func (c *HttpClient) Do(req *http.Request) (resp *http.Response, err error) {
 	// Always add the API key
 	req.Header.Set("X-API-KEY", c.Token)
 
+	started := time.Now()
 	if req.Method == "PATCH" {
 		// Sync operations can take longer - no timeout
 		tempClient := http.Client{Timeout: 0}
 		// Make sure headers are preserved
-		return tempClient.Do(req)
+		resp, err = tempClient.Do(req)
+	} else {
+		resp, err = c.client.Do(req)
+	}
+
+	status := 0
+	if err == nil {
+		status = resp.StatusCode
+	}
+	duration := time.Since(started)
+	// The token is sent as a header and never logged; the log also redacts
+	// it should it appear in a URL
+	if err != nil {
+		slog.Debug("Bazarr request failed", "method", req.Method, "url", req.URL.String(), "duration", duration, "err", err)
+	} else {
+		slog.Debug("Bazarr request", "method", req.Method, "url", req.URL.String(), "status", status, "duration", duration)
+	}
+	if RequestObserver != nil {
+		RequestObserver(req, status, duration)
 	}
-	return c.client.Do(req)
+	return resp, err
 }
 
 // The get request
@@ -1,11 +1,14 @@
 package config
 
 import (
-	"fmt"
+	"log/slog"
 	"net/url"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/regix1/bazarr-sync/internal/logging"
 	"github.com/spf13/viper"
 )
 
@@ -16,9 +19,22 @@ type Config struct {
 	ApiToken    string
 	BazarrUrl   string
 	ApiUrl      string
+	StateFile   string
 	Schedule    ScheduleConfig
 	Cache       CacheConfig
 	SyncOptions SyncOptionsConfig
+	Watch       WatchConfig
+	Server      ServerConfig
+	Webhook     WebhookConfig
+	Events      EventsConfig
+	// Where run results are sent
+	Notifications []NotificationConfig
+	Mqtt          MqttConfig
+	Log           LogConfig
+	// Where the record of every run is kept
+	History HistoryConfig
+	// Report written after scheduled runs
+	Report ReportConfig
 }
 
 type ScheduleConfig struct {
@@ -27,6 +43,139 @@ type ScheduleConfig struct {
 	SyncShows      bool
 	CronExpression string
 	Timezone       string
+	// Sync only subtitles from Bazarr's history since the last run
+	Incremental bool
+	// Optional schedule for a full library pass while Incremental is on
+	FullSyncCron string
+	// Dead man's switch pings of the scheduled jobs
+	Heartbeats []HeartbeatConfig
+}
+
+type HeartbeatConfig struct {
+	// Scheduled job to report on: sync or full-sync
+	Job string
+	// healthchecks (also for other services with /start and /fail URLs)
+	// or uptime-kuma
+	Type string
+	// Ping URL for a successful run
+	Url string
+	// Override the URLs derived from Url
+	StartUrl string
+	FailUrl  string
+}
+
+type WatchConfig struct {
+	SyncMovies bool
+	SyncShows  bool
+	// How often Bazarr's history is polled
+	Interval time.Duration
+	// How often a status line is logged, 0 to disable
+	HeartbeatInterval time.Duration
+}
+
+type WebhookConfig struct {
+	// How long to wait after an import before asking Bazarr for subtitles
+	Delay time.Duration
+	// How often Bazarr is asked again while subtitles are missing
+	PollInterval time.Duration
+	// How long after the import to give up waiting for subtitles
+	Timeout time.Duration
+}
+
+type EventsConfig struct {
+	// Follow Bazarr's live event stream in scheduler mode
+	Enabled bool
+	// How long to wait before reconnecting after the stream was lost
+	ReconnectDelay time.Duration
+}
+
+type MqttConfig struct {
+	// Broker address, for example "tcp://localhost:1883". Empty disables MQTT.
+	Broker   string
+	Username string
+	Password string
+	// Also identifies the device in Home Assistant
+	ClientId string
+	// State is published and commands are received under this topic
+	TopicPrefix string
+	// Announce the sensors to Home Assistant
+	Discovery       bool
+	DiscoveryPrefix string
+}
+
+type LogConfig struct {
+	// debug, info, warn or error
+	Level string
+	// text or json
+	Format string
+	// Also write the log to this file, empty for stderr only
+	File string
+	// Size in megabytes at which the file is rotated, 0 to never rotate
+	MaxSize int
+	// Rotated files to keep, 0 to keep all
+	MaxBackups int
+	// Age after which rotated files are removed, 0 to keep them
+	MaxAge time.Duration
+}
+
+type HistoryConfig struct {
+	// Directory of the run records, empty to keep no history
+	Dir string
+	// Runs to keep, 0 to keep all
+	MaxRuns int
+	// Age after which runs are removed, 0 to keep them
+	MaxAge time.Duration
+	// Runs in a row a subtitle must fail in to count as quarantined, 0 to
+	// never quarantine
+	QuarantineAfter int
+}
+
+type ReportConfig struct {
+	// HTML, Markdown or CSV file, chosen by the extension. {date}, {job} and
+	// {run_id} are replaced. Empty writes no report.
+	File string
+	// Scheduled jobs to write it for, for example "full-sync"; empty for all
+	Jobs []string
+}
+
+type NotificationConfig struct {
+	// Shown in errors, defaults to the type
+	Name string
+	// discord, slack, gotify, ntfy, webhook or email
+	Type string
+	// Webhook URL, Gotify server URL or ntfy topic URL
+	Url string
+	// Gotify application token or ntfy access token
+	Token string
+	// Which events are sent: run_finished, run_failed, failures
+	Events []string
+	// Number of failed subtitles from which the failures event is sent
+	FailureThreshold int
+	// Only runs with these triggers, for example "schedule"; empty for all
+	Triggers []string
+	// Go templates for the title and message, empty for the defaults
+	Title    string
+	Template string
+	// Mail server and addresses of email targets
+	Smtp SmtpConfig
+}
+
+type SmtpConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// starttls, tls or none
+	Encryption string
+	From       string
+	To         []string
+}
+
+type ServerConfig struct {
+	// Address of the control API, for example ":8080". Empty disables it.
+	Listen string
+	// Token required by the control API, empty for none
+	Token string
 }
 
 type CacheConfig struct {
@@ -41,9 +190,12 @@ type SyncOptionsConfig struct {
 }
 
 var cfg Config
+var cfgMu sync.RWMutex
 var CfgFile string
 
 func GetConfig() Config {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
 	return cfg
 }
 
@@ -65,37 +217,111 @@ func InitConfig() {
 	viper.SetDefault("Schedule.SyncShows", true)
 	viper.SetDefault("Schedule.CronExpression", "0 1 * * 0")
 	viper.SetDefault("Schedule.Timezone", "UTC")
+	viper.SetDefault("Schedule.Incremental", false)
+	viper.SetDefault("Schedule.FullSyncCron", "")
+	viper.SetDefault("StateFile", "sync-state.json")
 	viper.SetDefault("Cache.Enabled", false)
 	viper.SetDefault("Cache.MoviesCache", "movies-cache")
 	viper.SetDefault("Cache.ShowsCache", "shows-cache")
 	viper.SetDefault("SyncOptions.GoldenSection", false)
 	viper.SetDefault("SyncOptions.NoFramerateFix", false)
+	viper.SetDefault("Watch.SyncMovies", true)
+	viper.SetDefault("Watch.SyncShows", true)
+	viper.SetDefault("Watch.Interval", "5m")
+	viper.SetDefault("Watch.HeartbeatInterval", "1h")
+	viper.SetDefault("Server.Listen", "")
+	viper.SetDefault("Server.Token", "")
+	viper.SetDefault("Webhook.Delay", "2m")
+	viper.SetDefault("Webhook.PollInterval", "1m")
+	viper.SetDefault("Webhook.Timeout", "30m")
+	viper.SetDefault("Events.Enabled", false)
+	viper.SetDefault("Events.ReconnectDelay", "10s")
+	viper.SetDefault("Mqtt.ClientId", "bazarr-sync")
+	viper.SetDefault("Mqtt.TopicPrefix", "bazarr-sync")
+	viper.SetDefault("Mqtt.Discovery", true)
+	viper.SetDefault("Mqtt.DiscoveryPrefix", "homeassistant")
+	viper.SetDefault("Log.Level", "info")
+	viper.SetDefault("Log.Format", "text")
+	viper.SetDefault("Log.File", "")
+	viper.SetDefault("Log.MaxSize", 10)
+	viper.SetDefault("Log.MaxBackups", 5)
+	viper.SetDefault("Log.MaxAge", "0s")
+	viper.SetDefault("History.Dir", "history")
+	viper.SetDefault("History.MaxRuns", 100)
+	viper.SetDefault("History.MaxAge", "0s")
+	viper.SetDefault("History.QuarantineAfter", 3)
+	viper.SetDefault("Report.File", "")
 
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+		slog.Info("Using config file", "path", viper.ConfigFileUsed())
 	} else {
-		fmt.Fprintln(os.Stderr, "Configuration Error:", err)
-		fmt.Fprintln(os.Stderr, "Please supply a config.yaml file by using the flag --config or by placing the file in the same directory as bazarr-sync")
-		os.Exit(1)
+		slog.Error("Could not read the config file. Please supply a config.yaml file by using the flag --config or by placing the file in the same directory as bazarr-sync", "err", err)
+		// Exit code of a config error, see the README
+		os.Exit(2)
+	}
+
+	loaded, err := unmarshal()
+	if err != nil {
+		slog.Error("Invalid Bazarr address", "err", err)
 	}
+	logging.SetSecrets(Secrets(loaded)...)
 
-	viper.Unmarshal(&cfg)
+	cfgMu.Lock()
+	cfg = loaded
+	cfgMu.Unlock()
+}
+
+// Reload re-reads the config file in use and returns it when it is valid.
+// The new configuration is not active until it is passed to Apply, so the
+// caller can still reject it; on any error the previous one stays active.
+func Reload() (Config, error) {
+	if err := viper.ReadInConfig(); err != nil {
+		return GetConfig(), err
+	}
+
+	loaded, err := unmarshal()
+	if err != nil {
+		return GetConfig(), err
+	}
+	if err := Validate(loaded); err != nil {
+		return GetConfig(), err
+	}
+	return loaded, nil
+}
+
+// Apply makes a configuration returned by Reload the active one.
+func Apply(loaded Config) {
+	logging.SetSecrets(Secrets(loaded)...)
+
+	cfgMu.Lock()
+	cfg = loaded
+	cfgMu.Unlock()
+}
+
+func unmarshal() (Config, error) {
+	var loaded Config
+	if err := viper.Unmarshal(&loaded); err != nil {
+		return Config{}, err
+	}
 
 	var (
 		baseUrl string
 		err     error
 	)
 
-	if strings.Contains(cfg.Address, "/") {
-		baseUrl, err = url.JoinPath(cfg.Protocol + "://" + cfg.Address)
+	if strings.Contains(loaded.Address, "/") {
+		baseUrl, err = url.JoinPath(loaded.Protocol + "://" + loaded.Address)
 	} else {
-		baseUrl, err = url.JoinPath(cfg.Protocol + "://" + cfg.Address + ":" + cfg.Port)
-	}
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "URL Error:", err)
+		baseUrl, err = url.JoinPath(loaded.Protocol + "://" + loaded.Address + ":" + loaded.Port)
 	}
 
 	apiUrl, _ := url.JoinPath(baseUrl, "api/")
-	cfg.BazarrUrl = baseUrl
-	cfg.ApiUrl = apiUrl
+	loaded.BazarrUrl = baseUrl
+	loaded.ApiUrl = apiUrl
+	return loaded, err
+}
+
+// ConfigFile returns the path of the config file that was loaded.
+func ConfigFile() string {
+	return viper.ConfigFileUsed()
 }
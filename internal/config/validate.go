@@ -0,0 +1,187 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// File extensions of the report formats, matching report.Format
+var reportExtensions = []string{".html", ".htm", ".md", ".markdown", ".csv"}
+
+// Validate checks a configuration for values that would break a sync run or
+// the scheduler. All problems are reported together.
+func Validate(c Config) error {
+	var problems []string
+
+	if c.Address == "" {
+		problems = append(problems, "Address is empty")
+	}
+	if c.Protocol != "http" && c.Protocol != "https" {
+		problems = append(problems, fmt.Sprintf("Protocol must be http or https, got %q", c.Protocol))
+	}
+	if !strings.Contains(c.Address, "/") {
+		if _, err := strconv.Atoi(c.Port); err != nil {
+			problems = append(problems, fmt.Sprintf("Port must be a number, got %q", c.Port))
+		}
+	}
+	if c.ApiToken == "" {
+		problems = append(problems, "ApiToken is empty")
+	}
+
+	if c.Schedule.Enabled {
+		if _, err := cron.ParseStandard(c.Schedule.CronExpression); err != nil {
+			problems = append(problems, fmt.Sprintf("Schedule.CronExpression %q: %v", c.Schedule.CronExpression, err))
+		}
+		if c.Schedule.FullSyncCron != "" {
+			if _, err := cron.ParseStandard(c.Schedule.FullSyncCron); err != nil {
+				problems = append(problems, fmt.Sprintf("Schedule.FullSyncCron %q: %v", c.Schedule.FullSyncCron, err))
+			}
+		}
+		if _, err := time.LoadLocation(c.Schedule.Timezone); err != nil {
+			problems = append(problems, fmt.Sprintf("Schedule.Timezone %q: %v", c.Schedule.Timezone, err))
+		}
+	}
+
+	for i, hb := range c.Schedule.Heartbeats {
+		name := fmt.Sprintf("Schedule.Heartbeats[%d]", i)
+		if hb.Job != "sync" && hb.Job != "full-sync" {
+			problems = append(problems, fmt.Sprintf("%s.Job must be sync or full-sync, got %q", name, hb.Job))
+		}
+		if hb.Type != "healthchecks" && hb.Type != "uptime-kuma" {
+			problems = append(problems, fmt.Sprintf("%s.Type must be healthchecks or uptime-kuma, got %q", name, hb.Type))
+		}
+		urls := []struct{ field, value string }{{"Url", hb.Url}, {"StartUrl", hb.StartUrl}, {"FailUrl", hb.FailUrl}}
+		for _, u := range urls {
+			if u.value == "" && u.field != "Url" {
+				continue
+			}
+			if parsed, err := url.Parse(u.value); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				problems = append(problems, fmt.Sprintf("%s.%s must be an http or https URL", name, u.field))
+			}
+		}
+	}
+
+	if c.Cache.Enabled && (c.Cache.MoviesCache == "" || c.Cache.ShowsCache == "") {
+		problems = append(problems, "Cache.MoviesCache and Cache.ShowsCache must be set when the cache is enabled")
+	}
+
+	if c.Schedule.Incremental && c.StateFile == "" {
+		problems = append(problems, "StateFile must be set for incremental sync")
+	}
+
+	if c.Watch.Interval < time.Second {
+		problems = append(problems, fmt.Sprintf("Watch.Interval must be at least 1s, got %s", c.Watch.Interval))
+	}
+	if c.Watch.HeartbeatInterval < 0 {
+		problems = append(problems, "Watch.HeartbeatInterval must not be negative")
+	}
+
+	if c.Webhook.Delay < 0 {
+		problems = append(problems, "Webhook.Delay must not be negative")
+	}
+	if c.Webhook.PollInterval < time.Second {
+		problems = append(problems, fmt.Sprintf("Webhook.PollInterval must be at least 1s, got %s", c.Webhook.PollInterval))
+	}
+	if c.Webhook.Timeout < c.Webhook.Delay {
+		problems = append(problems, "Webhook.Timeout must not be shorter than Webhook.Delay")
+	}
+
+	if c.Events.Enabled && c.Events.ReconnectDelay < time.Second {
+		problems = append(problems, fmt.Sprintf("Events.ReconnectDelay must be at least 1s, got %s", c.Events.ReconnectDelay))
+	}
+
+	if c.Mqtt.Broker != "" {
+		u, err := url.Parse(c.Mqtt.Broker)
+		switch {
+		case err != nil || u.Host == "":
+			problems = append(problems, fmt.Sprintf("Mqtt.Broker must be an address like tcp://host:1883, got %q", c.Mqtt.Broker))
+		case u.Scheme != "tcp" && u.Scheme != "mqtt" && u.Scheme != "ssl" && u.Scheme != "tls" &&
+			u.Scheme != "mqtts" && u.Scheme != "ws" && u.Scheme != "wss":
+			problems = append(problems, fmt.Sprintf("Mqtt.Broker scheme must be tcp, mqtt, ssl, tls, mqtts, ws or wss, got %q", u.Scheme))
+		}
+		if c.Mqtt.TopicPrefix == "" || strings.ContainsAny(c.Mqtt.TopicPrefix, "+#") {
+			problems = append(problems, "Mqtt.TopicPrefix must be set and must not contain + or #")
+		}
+		if c.Mqtt.ClientId == "" {
+			problems = append(problems, "Mqtt.ClientId is empty")
+		}
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("Log.Level must be debug, info, warn or error, got %q", c.Log.Level))
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		problems = append(problems, fmt.Sprintf("Log.Format must be text or json, got %q", c.Log.Format))
+	}
+	if c.Log.MaxSize < 0 || c.Log.MaxBackups < 0 || c.Log.MaxAge < 0 {
+		problems = append(problems, "Log.MaxSize, Log.MaxBackups and Log.MaxAge must not be negative")
+	}
+	if c.History.MaxRuns < 0 || c.History.MaxAge < 0 || c.History.QuarantineAfter < 0 {
+		problems = append(problems, "History.MaxRuns, History.MaxAge and History.QuarantineAfter must not be negative")
+	}
+	if c.Report.File != "" && !slices.Contains(reportExtensions, strings.ToLower(filepath.Ext(c.Report.File))) {
+		problems = append(problems, fmt.Sprintf("Report.File must end in .html, .md or .csv, got %q", c.Report.File))
+	}
+	for _, job := range c.Report.Jobs {
+		if job != "sync" && job != "full-sync" {
+			problems = append(problems, fmt.Sprintf("Report.Jobs must contain sync or full-sync, got %q", job))
+		}
+	}
+
+	for i, n := range c.Notifications {
+		name := fmt.Sprintf("Notifications[%d]", i)
+		switch n.Type {
+		case "discord", "slack", "gotify", "ntfy", "webhook":
+			if u, err := url.Parse(n.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				problems = append(problems, fmt.Sprintf("%s.Url must be an http or https URL", name))
+			}
+		case "email":
+			if n.Smtp.Host == "" || n.Smtp.From == "" || len(n.Smtp.To) == 0 {
+				problems = append(problems, fmt.Sprintf("%s.Smtp: Host, From and To must be set for email", name))
+			}
+			switch n.Smtp.Encryption {
+			case "", "starttls", "tls", "none":
+			default:
+				problems = append(problems, fmt.Sprintf("%s.Smtp.Encryption must be starttls, tls or none, got %q", name, n.Smtp.Encryption))
+			}
+			if n.Smtp.Port < 0 || n.Smtp.Port > 65535 {
+				problems = append(problems, fmt.Sprintf("%s.Smtp.Port must be a port number, got %d", name, n.Smtp.Port))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("%s.Type must be discord, slack, gotify, ntfy, webhook or email, got %q", name, n.Type))
+		}
+		if n.Type == "gotify" && n.Token == "" {
+			problems = append(problems, fmt.Sprintf("%s.Token must be set for Gotify", name))
+		}
+		for _, event := range n.Events {
+			if event != "run_finished" && event != "run_failed" && event != "failures" && event != "quarantined" {
+				problems = append(problems, fmt.Sprintf("%s.Events: unknown event %q, expected run_finished, run_failed, failures or quarantined", name, event))
+			}
+		}
+		if n.FailureThreshold < 0 {
+			problems = append(problems, fmt.Sprintf("%s.FailureThreshold must not be negative", name))
+		}
+		if _, err := template.New("title").Parse(n.Title); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.Title: %v", name, err))
+		}
+		if _, err := template.New("message").Parse(n.Template); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.Template: %v", name, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
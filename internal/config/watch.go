@@ -0,0 +1,157 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var errNoConfigFile = errors.New("no config file in use")
+
+// Fields computed from other fields; a change to them is already reported
+// through the fields they are derived from.
+var derivedFields = map[string]bool{
+	"BazarrUrl": true,
+	"ApiUrl":    true,
+}
+
+// Fields whose values must never be printed.
+var secretFields = map[string]bool{
+	"ApiToken": true,
+	"Token":    true,
+	"Password": true,
+	// Heartbeat URLs contain the check's secret ID
+	"StartUrl": true,
+	"FailUrl":  true,
+	// Chat webhook URLs carry their credentials
+	"Url": true,
+}
+
+// Secrets returns the values of all secret settings, such as the API token,
+// so they can be kept out of logs.
+func Secrets(c Config) []string {
+	var values []string
+	collectSecrets(reflect.ValueOf(c), &values)
+	return values
+}
+
+func collectSecrets(v reflect.Value, values *[]string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			collectSecrets(fv, values)
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			for j := 0; j < fv.Len(); j++ {
+				collectSecrets(fv.Index(j), values)
+			}
+		case secretFields[field.Name] && field.Type.Kind() == reflect.String && fv.String() != "":
+			*values = append(*values, fv.String())
+		}
+	}
+}
+
+// Watch calls onChange whenever the config file in use is written or replaced.
+// Editors often save in several steps, so events are debounced into one call.
+// The returned function stops watching.
+func Watch(onChange func()) (func(), error) {
+	if ConfigFile() == "" {
+		return nil, errNoConfigFile
+	}
+	file, err := filepath.Abs(ConfigFile())
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// Watch the directory rather than the file, so saves that replace the file
+	// (write to a temp file, then rename) are still noticed.
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				name, _ := filepath.Abs(event.Name)
+				if name != file || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(500*time.Millisecond, onChange)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("Config watch error", "err", err)
+			}
+		}
+	}()
+
+	return func() { watcher.Close() }, nil
+}
+
+// Diff lists the settings that differ between two configurations, one
+// "Section.Field: old -> new" line per change. Secrets are never printed.
+func Diff(old, new Config) []string {
+	var changes []string
+	diffStruct("", reflect.ValueOf(old), reflect.ValueOf(new), &changes)
+	return changes
+}
+
+func diffStruct(prefix string, a, b reflect.Value, changes *[]string) {
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if derivedFields[field.Name] {
+			continue
+		}
+
+		name := field.Name
+		if prefix != "" {
+			name = prefix + "." + field.Name
+		}
+
+		av, bv := a.Field(i), b.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			diffStruct(name, av, bv, changes)
+			continue
+		}
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
+			if av.Len() != bv.Len() {
+				*changes = append(*changes, fmt.Sprintf("%s: %d entries -> %d entries", name, av.Len(), bv.Len()))
+				continue
+			}
+			for j := 0; j < av.Len(); j++ {
+				diffStruct(fmt.Sprintf("%s[%d]", name, j), av.Index(j), bv.Index(j), changes)
+			}
+			continue
+		}
+		if reflect.DeepEqual(av.Interface(), bv.Interface()) {
+			continue
+		}
+		if secretFields[field.Name] {
+			*changes = append(*changes, name+": (changed)")
+			continue
+		}
+		*changes = append(*changes, fmt.Sprintf("%s: %v -> %v", name, av.Interface(), bv.Interface()))
+	}
+}
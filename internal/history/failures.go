@@ -0,0 +1,104 @@
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+// Failure is a subtitle that failed to sync in one or more recorded runs.
+type Failure struct {
+	Kind     string `json:"kind"`
+	Id       int    `json:"id"`
+	Title    string `json:"title"`
+	Media    string `json:"media,omitempty"`
+	Language string `json:"language,omitempty"`
+	Path     string `json:"path"`
+	// Runs in which the sync was tried, and failed
+	Attempts int `json:"attempts"`
+	Failures int `json:"failures"`
+	// Failed is true while the latest attempt still failed
+	Failed bool `json:"failed"`
+	// Runs in a row, up to the latest attempt, in which the sync failed
+	Consecutive int       `json:"consecutive"`
+	FirstFailed time.Time `json:"first_failed"`
+	LastFailed  time.Time `json:"last_failed"`
+	LastMessage string    `json:"last_message"`
+	LastRunId   string    `json:"last_run_id"`
+}
+
+// Failures aggregates the failed subtitles of runs, given newest first, by
+// path. The subtitles that failed most often come first.
+func Failures(runs []Run) []Failure {
+	byPath := make(map[string]*Failure)
+	latest := make(map[string]bool) // paths whose latest attempt was seen
+	streak := make(map[string]bool) // paths whose failures in a row ended
+
+	for _, run := range runs {
+		for _, sub := range run.Subtitles {
+			if sub.Outcome == "skipped" {
+				continue
+			}
+			f, found := byPath[sub.Path]
+			if !found {
+				f = &Failure{Kind: sub.Kind, Id: sub.Id, Title: sub.Title, Media: sub.Media,
+					Language: sub.Language, Path: sub.Path}
+				byPath[sub.Path] = f
+			}
+			f.Attempts++
+			if !latest[sub.Path] {
+				latest[sub.Path] = true
+				f.Failed = sub.Outcome == "failed"
+			}
+			if sub.Outcome != "failed" {
+				streak[sub.Path] = true
+				continue
+			}
+			if !streak[sub.Path] {
+				f.Consecutive++
+			}
+			f.Failures++
+			if f.LastFailed.IsZero() {
+				f.LastFailed, f.LastMessage, f.LastRunId = sub.Time, sub.Message, run.Id
+			}
+			f.FirstFailed = sub.Time
+		}
+	}
+
+	var failures []Failure
+	for _, f := range byPath {
+		if f.Failures > 0 {
+			failures = append(failures, *f)
+		}
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].Failures != failures[j].Failures {
+			return failures[i].Failures > failures[j].Failures
+		}
+		return failures[i].LastFailed.After(failures[j].LastFailed)
+	})
+	return failures
+}
+
+// Quarantined reports whether the subtitle failed in at least after runs in
+// a row, up to its latest attempt. A subtitle is never quarantined when
+// after is 0.
+func (f Failure) Quarantined(after int) bool {
+	return after > 0 && f.Consecutive >= after
+}
+
+// Latest returns the latest sync outcome of every subtitle in runs, given
+// newest first, by path. Skipped subtitles were not tried and do not count.
+func Latest(runs []Run) map[string]Subtitle {
+	latest := make(map[string]Subtitle)
+	for _, run := range runs {
+		for _, sub := range run.Subtitles {
+			if sub.Outcome == "skipped" || sub.Path == "" {
+				continue
+			}
+			if _, found := latest[sub.Path]; !found {
+				latest[sub.Path] = sub
+			}
+		}
+	}
+	return latest
+}
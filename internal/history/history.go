@@ -0,0 +1,266 @@
+// Package history keeps a record of every sync run on disk: when and why it
+// ran, with which options, and what happened to each subtitle. Each run is
+// one JSON file named after its ID, so the files sort oldest first.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Get for a run that is not in the store.
+var ErrNotFound = errors.New("no such run")
+
+// Summary counts the subtitles of a run or part by outcome.
+type Summary struct {
+	Success       int `json:"success"`
+	AlreadySynced int `json:"already_synced"`
+	Skipped       int `json:"skipped"`
+	Failed        int `json:"failed"`
+}
+
+// Options are the settings a part ran with.
+type Options struct {
+	GoldenSection  bool `json:"golden_section,omitempty"`
+	NoFramerateFix bool `json:"no_framerate_fix,omitempty"`
+	Cache          bool `json:"cache,omitempty"`
+	// Radarr movie IDs or Sonarr series IDs, empty for the whole library
+	Ids []int `json:"ids,omitempty"`
+	// Movie or episode ID the part continued from
+	ContinueFrom int `json:"continue_from,omitempty"`
+	// Window of subtitle downloads the part was limited to
+	Since string `json:"since,omitempty"`
+	Until string `json:"until,omitempty"`
+}
+
+// Part is one pass of a run, such as all movies or all shows.
+type Part struct {
+	Name     string    `json:"name"`
+	Options  Options   `json:"options"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Summary  Summary   `json:"summary"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Duration is how long the part took.
+func (p Part) Duration() time.Duration {
+	return p.Finished.Sub(p.Started)
+}
+
+// Subtitle is the outcome of one subtitle: success, already_synced, failed
+// or skipped. Message holds the error or the reason it was skipped.
+type Subtitle struct {
+	Time     time.Time `json:"time"`
+	Part     string    `json:"part"`
+	Kind     string    `json:"kind"`
+	Id       int       `json:"id"`
+	Title    string    `json:"title"`
+	Media    string    `json:"media,omitempty"`
+	Language string    `json:"language,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Outcome  string    `json:"outcome"`
+	Message  string    `json:"message,omitempty"`
+	// How long Bazarr took to sync it, retries included
+	Duration float64 `json:"duration_seconds,omitempty"`
+}
+
+// Entry is what a listing shows of a run: everything but its subtitles.
+type Entry struct {
+	Id       string    `json:"run_id"`
+	Trigger  string    `json:"trigger"`
+	Job      string    `json:"job,omitempty"`
+	RetryOf  string    `json:"retry_of,omitempty"`
+	Status   string    `json:"status"` // completed, failed or cancelled
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Summary  Summary   `json:"summary"`
+	Parts    []Part    `json:"parts"`
+}
+
+// Run is the full record of a run.
+type Run struct {
+	Entry
+	Subtitles []Subtitle `json:"subtitles"`
+}
+
+// Duration is how long the run took.
+func (e Entry) Duration() time.Duration {
+	return e.Finished.Sub(e.Started)
+}
+
+// Store is a directory of run records.
+type Store struct {
+	dir string
+}
+
+func Open(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Save writes the record of a run, replacing it atomically so a reader never
+// sees half a file. Records are not indented: a full library pass lists
+// every subtitle.
+func (s *Store) Save(run Run) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".run-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(run.Id))
+}
+
+// ids returns the IDs of the stored runs, oldest first.
+func (s *Store) ids() ([]string, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ids []string
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, ".json"))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// List returns up to limit runs, newest first; 0 returns all of them.
+func (s *Store) List(limit int) ([]Entry, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for i := len(ids) - 1; i >= 0; i-- {
+		if limit > 0 && len(entries) == limit {
+			break
+		}
+		var entry Entry
+		if err := s.read(ids[i], &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Runs returns the full records of up to limit runs, newest first; 0
+// returns all of them.
+func (s *Store) Runs(limit int) ([]Run, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+	var runs []Run
+	for i := len(ids) - 1; i >= 0; i-- {
+		if limit > 0 && len(runs) == limit {
+			break
+		}
+		var run Run
+		if err := s.read(ids[i], &run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// Get returns a run by its ID. A unique prefix of the ID is enough, and
+// "last" is the most recent run.
+func (s *Store) Get(id string) (Run, error) {
+	var run Run
+	ids, err := s.ids()
+	if err != nil {
+		return run, err
+	}
+
+	var matches []string
+	for _, stored := range ids {
+		if stored == id {
+			matches = []string{stored}
+			break
+		}
+		if strings.HasPrefix(stored, id) {
+			matches = append(matches, stored)
+		}
+	}
+	if id == "last" && len(ids) > 0 {
+		matches = ids[len(ids)-1:]
+	}
+
+	switch len(matches) {
+	case 0:
+		return run, fmt.Errorf("%w: %s", ErrNotFound, id)
+	case 1:
+		err = s.read(matches[0], &run)
+		return run, err
+	}
+	return run, fmt.Errorf("%q matches %d runs, give more of the ID", id, len(matches))
+}
+
+func (s *Store) read(id string, v any) error {
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", s.path(id), err)
+	}
+	return nil
+}
+
+// Prune removes the oldest runs beyond maxRuns and the runs recorded more
+// than maxAge ago. A limit of 0 does not apply.
+func (s *Store) Prune(maxRuns int, maxAge time.Duration) error {
+	ids, err := s.ids()
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		remove := maxRuns > 0 && i < len(ids)-maxRuns
+		if !remove && maxAge > 0 {
+			if info, err := os.Stat(s.path(id)); err == nil && time.Since(info.ModTime()) > maxAge {
+				remove = true
+			}
+		}
+		if remove {
+			if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
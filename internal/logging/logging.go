@@ -0,0 +1,248 @@
+// Package logging sets up the structured logger used for diagnostics:
+// errors, warnings and what the daemon is doing. The progress of a sync and
+// its summary are output for people and stay on stdout.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configures the logger.
+type Options struct {
+	// debug, info, warn or error
+	Level string
+	// text or json
+	Format string
+	// Also write the log to this file, empty for stderr only
+	File string
+	// Size in megabytes at which the file is rotated, 0 to never rotate
+	MaxSize int
+	// Rotated files to keep, 0 to keep all
+	MaxBackups int
+	// Age after which rotated files are removed, 0 to keep them
+	MaxAge time.Duration
+}
+
+// Replaces secrets in log output
+const redacted = "[REDACTED]"
+
+// Attribute keys whose values are never logged
+var secretKeys = map[string]bool{
+	"token":     true,
+	"api_token": true,
+	"apikey":    true,
+	"api_key":   true,
+	"password":  true,
+}
+
+var (
+	mu      sync.Mutex
+	file    *rotatingFile
+	secrets *strings.Replacer
+)
+
+// ParseLevel reads a level name.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("log level must be debug, info, warn or error, got %q", name)
+	}
+	return level, nil
+}
+
+// Setup installs the logger as slog's default. It can be called again, for
+// example after the configuration was loaded or reloaded; a log file that
+// was opened before is closed unless it is still in use.
+func Setup(opts Options) error {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+	if opts.Format != "text" && opts.Format != "json" {
+		return fmt.Errorf("log format must be text or json, got %q", opts.Format)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if file != nil && (opts.File == "" || file.path != opts.File) {
+		file.Close()
+		file = nil
+	}
+	if opts.File != "" {
+		if file == nil {
+			f, err := openRotatingFile(opts.File)
+			if err != nil {
+				return err
+			}
+			file = f
+		}
+		file.setLimits(int64(opts.MaxSize)*1024*1024, opts.MaxBackups, opts.MaxAge)
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level, ReplaceAttr: formatDuration}
+	handlers := []slog.Handler{newHandler(os.Stderr, opts.Format, handlerOpts)}
+	if file != nil {
+		handlers = append(handlers, newHandler(file, opts.Format, handlerOpts))
+	}
+	slog.SetDefault(slog.New(&redactHandler{next: teeHandler(handlers)}))
+	return nil
+}
+
+func newHandler(w io.Writer, format string, opts *slog.HandlerOptions) slog.Handler {
+	if format == "json" {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// formatDuration writes durations as "1m30s" rather than in nanoseconds,
+// which is what the JSON handler would do.
+func formatDuration(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindDuration {
+		return slog.String(a.Key, a.Value.Duration().String())
+	}
+	return a
+}
+
+// SetSecrets sets the values that are replaced wherever they appear in a
+// log record, such as the API token. Empty values are ignored.
+func SetSecrets(values ...string) {
+	var pairs []string
+	for _, v := range values {
+		if v != "" {
+			pairs = append(pairs, v, redacted)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	secrets = strings.NewReplacer(pairs...)
+}
+
+// Redact replaces the secrets in s.
+func Redact(s string) string {
+	mu.Lock()
+	r := secrets
+	mu.Unlock()
+	if r == nil {
+		return s
+	}
+	return r.Replace(s)
+}
+
+// Close flushes and closes the log file, if any.
+func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+		file = nil
+	}
+}
+
+// redactHandler removes secrets from messages and attributes before they
+// reach the output.
+type redactHandler struct {
+	next slog.Handler
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	clean := slog.NewRecord(r.Time, r.Level, Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		clean.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, clean)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = redactAttr(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(clean)}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	if secretKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(v.String()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		clean := make([]any, len(attrs))
+		for i, attr := range attrs {
+			clean[i] = redactAttr(attr)
+		}
+		return slog.Group(a.Key, clean...)
+	case slog.KindAny:
+		// Errors and other values are logged as text; they may quote a URL
+		// with the token in it
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+		if s, ok := v.Any().(fmt.Stringer); ok {
+			return slog.String(a.Key, Redact(s.String()))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// teeHandler sends each record to several handlers.
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var first error
+	for _, h := range t {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
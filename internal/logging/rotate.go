@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Layout of the timestamp added to rotated files:
+// bazarr-sync.log becomes bazarr-sync-20261018-150405.123.log. Milliseconds
+// keep files rotated within the same second apart.
+const backupLayout = "20060102-150405.000"
+
+// rotatingFile is a log file that is renamed and started anew once it
+// reaches maxSize, keeping a limited number of rotated files.
+type rotatingFile struct {
+	path string
+
+	mu         sync.Mutex
+	f          *os.File
+	size       int64
+	maxSize    int64 // 0 to never rotate
+	maxBackups int
+	maxAge     time.Duration
+}
+
+func openRotatingFile(path string) (*rotatingFile, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &rotatingFile{path: path, f: f, size: info.Size()}, nil
+}
+
+func (r *rotatingFile) setLimits(maxSize int64, maxBackups int, maxAge time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxSize, r.maxBackups, r.maxAge = maxSize, maxBackups, maxAge
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+// rotate moves the current file aside, opens a new one and removes rotated
+// files beyond the limits. The caller must hold r.mu.
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(r.path)
+	stamp := time.Now()
+	backup := strings.TrimSuffix(r.path, ext) + "-" + stamp.Format(backupLayout) + ext
+	for {
+		// Never overwrite an earlier backup
+		if _, err := os.Lstat(backup); os.IsNotExist(err) {
+			break
+		}
+		stamp = stamp.Add(time.Millisecond)
+		backup = strings.TrimSuffix(r.path, ext) + "-" + stamp.Format(backupLayout) + ext
+	}
+	if err := os.Rename(r.path, backup); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		r.f = nil
+		return err
+	}
+	r.f, r.size = f, 0
+	r.prune()
+	return nil
+}
+
+// prune removes the rotated files that are too many or too old. The
+// timestamp in their names sorts them oldest first.
+func (r *rotatingFile) prune() {
+	ext := filepath.Ext(r.path)
+	prefix := strings.TrimSuffix(r.path, ext) + "-"
+	matches, _ := filepath.Glob(prefix + "*" + ext)
+	var backups []string
+	for _, match := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(match, prefix), ext)
+		if _, err := time.Parse(backupLayout, stamp); err == nil {
+			backups = append(backups, match)
+		}
+	}
+	sort.Strings(backups)
+
+	for i, backup := range backups {
+		remove := r.maxBackups > 0 && i < len(backups)-r.maxBackups
+		if !remove && r.maxAge > 0 {
+			if info, err := os.Stat(backup); err == nil && time.Since(info.ModTime()) > r.maxAge {
+				remove = true
+			}
+		}
+		if remove {
+			os.Remove(backup)
+		}
+	}
+}
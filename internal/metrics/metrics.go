@@ -0,0 +1,155 @@
+// Package metrics collects the Prometheus metrics served by the scheduler
+// daemon on /metrics.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "bazarr_sync"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	subtitles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "subtitles_total",
+		Help:      "Subtitles handled, by outcome (success, already_synced, failed, skipped), media kind and language.",
+	}, []string{"outcome", "kind", "language"})
+
+	syncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sync_duration_seconds",
+		Help:      "Duration of subtitle sync requests to Bazarr, by outcome.",
+		Buckets:   []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"outcome"})
+
+	syncsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "syncs_in_flight",
+		Help:      "Subtitle sync requests waiting for Bazarr.",
+	})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "bazarr_request_duration_seconds",
+		Help:      "Latency of requests to the Bazarr API, by method and path.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "path"})
+
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bazarr_requests_total",
+		Help:      "Requests to the Bazarr API, by method, path and status code (0 when Bazarr could not be reached).",
+	}, []string{"method", "path", "status"})
+
+	lastRunTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_last_run_timestamp_seconds",
+		Help:      "Time the last run of a job finished.",
+	}, []string{"job"})
+
+	lastRunSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_last_run_success",
+		Help:      "Whether the last run of a job completed without an error (1) or not (0).",
+	}, []string{"job"})
+
+	lastRunSubtitles = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_last_run_subtitles",
+		Help:      "Subtitles handled by the last run of a job, by outcome.",
+	}, []string{"job", "outcome"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		subtitles, syncDuration, syncsInFlight, requestDuration, requests,
+		lastRunTimestamp, lastRunSuccess, lastRunSubtitles,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RecordSubtitle counts a handled subtitle.
+func RecordSubtitle(outcome string, kind string, language string) {
+	subtitles.WithLabelValues(outcome, kind, language).Inc()
+}
+
+// SyncStarted marks a sync request as in flight. The returned function
+// records its duration once the outcome is known.
+func SyncStarted() func(outcome string) {
+	started := time.Now()
+	syncsInFlight.Inc()
+	return func(outcome string) {
+		syncsInFlight.Dec()
+		syncDuration.WithLabelValues(outcome).Observe(time.Since(started).Seconds())
+	}
+}
+
+// RecordRequest records a request to the Bazarr API. Status is 0 when
+// Bazarr could not be reached.
+func RecordRequest(method string, path string, status int, duration time.Duration) {
+	requestDuration.WithLabelValues(method, path).Observe(duration.Seconds())
+	requests.WithLabelValues(method, path, strconv.Itoa(status)).Inc()
+}
+
+// RunCounts are the subtitle counts of a finished run.
+type RunCounts struct {
+	Success       int
+	AlreadySynced int
+	Skipped       int
+	Failed        int
+}
+
+// RecordRun records the result of a finished run of a job.
+func RecordRun(job string, finished time.Time, ok bool, counts RunCounts) {
+	lastRunTimestamp.WithLabelValues(job).Set(float64(finished.Unix()))
+	success := 0.0
+	if ok {
+		success = 1
+	}
+	lastRunSuccess.WithLabelValues(job).Set(success)
+	lastRunSubtitles.WithLabelValues(job, "success").Set(float64(counts.Success))
+	lastRunSubtitles.WithLabelValues(job, "already_synced").Set(float64(counts.AlreadySynced))
+	lastRunSubtitles.WithLabelValues(job, "skipped").Set(float64(counts.Skipped))
+	lastRunSubtitles.WithLabelValues(job, "failed").Set(float64(counts.Failed))
+}
+
+// WatchQueue exposes the number of queued runs, read from queued at every
+// scrape.
+func WatchQueue(queued func() int) {
+	registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Runs waiting for the current run to finish.",
+	}, func() float64 {
+		return float64(queued())
+	}))
+}
+
+// WatchCache exposes the number of cached subtitles per media kind ("movies"
+// or "shows"), read from size at every scrape.
+func WatchCache(size func(kind string) int) {
+	for _, kind := range []string{"movies", "shows"} {
+		registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "cache_entries",
+			Help:        "Subtitles recorded in the cache as synced.",
+			ConstLabels: prometheus.Labels{"kind": kind},
+		}, func() float64 {
+			return float64(size(kind))
+		}))
+	}
+}
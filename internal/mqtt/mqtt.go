@@ -0,0 +1,241 @@
+// Package mqtt publishes the scheduler's state to an MQTT broker, announces
+// it to Home Assistant through MQTT discovery and receives commands.
+package mqtt
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/regix1/bazarr-sync/internal/config"
+)
+
+// Topics below Mqtt.TopicPrefix
+const (
+	stateTopic        = "state"        // State as JSON, retained
+	availabilityTopic = "availability" // "online" or "offline", retained
+	commandTopic      = "command"      // see Command
+)
+
+// How long to wait for the broker when publishing the final messages
+const closeTimeout = time.Second
+
+// LastRun summarizes the last finished run.
+type LastRun struct {
+	Id            string `json:"id"`
+	Trigger       string `json:"trigger"`
+	Job           string `json:"job"`
+	Status        string `json:"status"`   // completed, failed or cancelled
+	Finished      string `json:"finished"` // RFC 3339, empty before the first run
+	Synced        int    `json:"synced"`
+	AlreadySynced int    `json:"already_synced"`
+	Skipped       int    `json:"skipped"`
+	Failed        int    `json:"failed"`
+}
+
+// State is what is published on the state topic.
+type State struct {
+	State    string  `json:"state"` // idle or running
+	RunId    string  `json:"run_id"`
+	Trigger  string  `json:"trigger"`
+	Job      string  `json:"job"`
+	Current  string  `json:"current"`  // item being processed
+	Progress int     `json:"progress"` // percent of the current part
+	LastRun  LastRun `json:"last_run"`
+	// Subtitles whose last sync failed
+	Failures int `json:"failures"`
+}
+
+// Command is a request received on the command topic: "run" runs the sync
+// job, "run <job>" another job and "cancel" stops the current run.
+type Command struct {
+	Name string // run or cancel
+	Job  string // for run, empty for the default job
+}
+
+// ParseCommand reads a command message. It returns false for messages that
+// are not a known command.
+func ParseCommand(payload string) (Command, bool) {
+	fields := strings.Fields(strings.ToLower(payload))
+	switch {
+	case len(fields) == 1 && fields[0] == "cancel":
+		return Command{Name: "cancel"}, true
+	case len(fields) == 1 && fields[0] == "run":
+		return Command{Name: "run"}, true
+	case len(fields) == 2 && fields[0] == "run":
+		return Command{Name: "run", Job: fields[1]}, true
+	}
+	return Command{}, false
+}
+
+// Client is the connection to the broker.
+type Client struct {
+	cfg    config.MqttConfig
+	client paho.Client
+
+	mu    sync.Mutex
+	state State
+}
+
+// Connect starts connecting to the broker and returns right away; the
+// connection is retried in the background until it succeeds and restored
+// when it is lost. onStatus is called on every connect and disconnect, and
+// onCommand with the payload of every message on the command topic.
+func Connect(cfg config.MqttConfig, onCommand func(payload string), onStatus func(connected bool, err error)) *Client {
+	c := &Client{cfg: cfg, state: State{State: "idle"}}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientId).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetCleanSession(true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10*time.Second).
+		SetWill(c.topic(availabilityTopic), "offline", 1, true)
+
+	opts.SetOnConnectHandler(func(client paho.Client) {
+		client.Subscribe(c.topic(commandTopic), 1, func(_ paho.Client, msg paho.Message) {
+			onCommand(string(msg.Payload()))
+		})
+		client.Publish(c.topic(availabilityTopic), 1, true, "online")
+		if cfg.Discovery {
+			c.announce(client)
+		}
+		c.mu.Lock()
+		c.publishState(client)
+		c.mu.Unlock()
+		onStatus(true, nil)
+	})
+	opts.SetConnectionLostHandler(func(_ paho.Client, err error) {
+		onStatus(false, err)
+	})
+
+	c.client = paho.NewClient(opts)
+	c.client.Connect()
+	return c
+}
+
+// Update changes the published state. Nothing is sent when the change
+// leaves the state as it was.
+func (c *Client) Update(change func(state *State)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := c.state
+	change(&c.state)
+	if c.state != previous && c.client.IsConnectionOpen() {
+		c.publishState(c.client)
+	}
+}
+
+// Close marks the device offline and disconnects.
+func (c *Client) Close() {
+	if c.client.IsConnectionOpen() {
+		c.client.Publish(c.topic(availabilityTopic), 1, true, "offline").WaitTimeout(closeTimeout)
+	}
+	c.client.Disconnect(uint(closeTimeout / time.Millisecond))
+}
+
+// publishState sends the current state. The caller must hold c.mu.
+func (c *Client) publishState(client paho.Client) {
+	payload, _ := json.Marshal(c.state)
+	client.Publish(c.topic(stateTopic), 0, true, payload)
+}
+
+func (c *Client) topic(name string) string {
+	return strings.TrimSuffix(c.cfg.TopicPrefix, "/") + "/" + name
+}
+
+// entity is one sensor or button announced to Home Assistant.
+type entity struct {
+	component string
+	id        string
+	config    map[string]any
+}
+
+var invalidNodeId = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// announce publishes the Home Assistant discovery messages, which make the
+// sensors and buttons appear on a "bazarr-sync" device.
+func (c *Client) announce(client paho.Client) {
+	node := invalidNodeId.ReplaceAllString(c.cfg.ClientId, "_")
+	device := map[string]any{
+		"identifiers": []string{node},
+		"name":        "bazarr-sync",
+		"model":       "bazarr-sync",
+	}
+
+	entities := []entity{
+		{"sensor", "state", map[string]any{
+			"name":           "State",
+			"icon":           "mdi:subtitles",
+			"value_template": "{{ value_json.state }}",
+		}},
+		{"sensor", "current", map[string]any{
+			"name":           "Current item",
+			"icon":           "mdi:movie-open",
+			"value_template": "{{ value_json.current }}",
+		}},
+		{"sensor", "progress", map[string]any{
+			"name":                "Progress",
+			"icon":                "mdi:progress-clock",
+			"unit_of_measurement": "%",
+			"value_template":      "{{ value_json.progress }}",
+		}},
+		{"sensor", "last_run", map[string]any{
+			"name":                     "Last run",
+			"icon":                     "mdi:history",
+			"value_template":           "{{ value_json.last_run.status or 'none' }}",
+			"json_attributes_topic":    c.topic(stateTopic),
+			"json_attributes_template": "{{ value_json.last_run | tojson }}",
+		}},
+		{"sensor", "last_run_finished", map[string]any{
+			"name":           "Last run finished",
+			"device_class":   "timestamp",
+			"value_template": "{{ value_json.last_run.finished or none }}",
+		}},
+		{"sensor", "last_run_synced", map[string]any{
+			"name":           "Last run synced",
+			"icon":           "mdi:check-circle",
+			"value_template": "{{ value_json.last_run.synced }}",
+		}},
+		{"sensor", "last_run_failed", map[string]any{
+			"name":           "Last run failed",
+			"icon":           "mdi:alert-circle",
+			"value_template": "{{ value_json.last_run.failed }}",
+		}},
+		{"sensor", "failures", map[string]any{
+			"name":           "Failed subtitles",
+			"icon":           "mdi:alert",
+			"value_template": "{{ value_json.failures }}",
+		}},
+		{"button", "run", map[string]any{
+			"name":          "Run sync",
+			"icon":          "mdi:play",
+			"command_topic": c.topic(commandTopic),
+			"payload_press": "run",
+		}},
+		{"button", "cancel", map[string]any{
+			"name":          "Cancel run",
+			"icon":          "mdi:stop",
+			"command_topic": c.topic(commandTopic),
+			"payload_press": "cancel",
+		}},
+	}
+
+	for _, e := range entities {
+		e.config["unique_id"] = node + "_" + e.id
+		e.config["availability_topic"] = c.topic(availabilityTopic)
+		e.config["device"] = device
+		if e.component == "sensor" {
+			e.config["state_topic"] = c.topic(stateTopic)
+		}
+		payload, _ := json.Marshal(e.config)
+		topic := strings.TrimSuffix(c.cfg.DiscoveryPrefix, "/") + "/" + e.component + "/" + node + "/" + e.id + "/config"
+		client.Publish(topic, 1, true, payload)
+	}
+}
@@ -0,0 +1,177 @@
+package mqtt_test
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+	"github.com/regix1/bazarr-sync/internal/mqtt"
+	"github.com/regix1/bazarr-sync/internal/mqtt/mqtttest"
+)
+
+// connect starts a broker and a client of it with discovery on, and waits
+// until the client subscribed to the command topic. It returns the commands
+// the client receives.
+func connect(t *testing.T) (*mqtttest.Broker, *mqtt.Client, <-chan string) {
+	broker, err := mqtttest.NewBroker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { broker.Close() })
+
+	commands := make(chan string, 16)
+	connected := make(chan struct{}, 1)
+	cfg := config.MqttConfig{
+		Broker:          broker.URL,
+		ClientId:        "bazarr-sync.test",
+		TopicPrefix:     "bazarr-sync/",
+		Discovery:       true,
+		DiscoveryPrefix: "homeassistant",
+	}
+	client := mqtt.Connect(cfg, func(payload string) { commands <- payload }, func(ok bool, err error) {
+		if ok {
+			connected <- struct{}{}
+		}
+	})
+	t.Cleanup(client.Close)
+
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not connect to the broker")
+	}
+	waitFor(t, "subscription to the command topic", func() bool {
+		return broker.Subscribed("bazarr-sync/command")
+	})
+	return broker, client, commands
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// retainedState returns the state retained on the state topic.
+func retainedState(broker *mqtttest.Broker) (mqtt.State, bool) {
+	payload, found := broker.Retained("bazarr-sync/state")["bazarr-sync/state"]
+	if !found {
+		return mqtt.State{}, false
+	}
+	var state mqtt.State
+	return state, json.Unmarshal([]byte(payload), &state) == nil
+}
+
+func TestDiscovery(t *testing.T) {
+	broker, _, _ := connect(t)
+
+	var configs map[string]string
+	waitFor(t, "the discovery messages", func() bool {
+		configs = broker.Retained("homeassistant/#")
+		return len(configs) == 10
+	})
+
+	var state map[string]any
+	if err := json.Unmarshal([]byte(configs["homeassistant/sensor/bazarr-sync_test/state/config"]), &state); err != nil {
+		t.Fatalf("state sensor: %v", err)
+	}
+	want := map[string]any{
+		"name":               "State",
+		"unique_id":          "bazarr-sync_test_state",
+		"state_topic":        "bazarr-sync/state",
+		"availability_topic": "bazarr-sync/availability",
+		"value_template":     "{{ value_json.state }}",
+	}
+	for key, value := range want {
+		if state[key] != value {
+			t.Errorf("state sensor %s = %v, want %v", key, state[key], value)
+		}
+	}
+	device, _ := state["device"].(map[string]any)
+	if device["name"] != "bazarr-sync" || !slices.Equal(toStrings(device["identifiers"]), []string{"bazarr-sync_test"}) {
+		t.Errorf("state sensor device = %v, want bazarr-sync identified by the client ID", state["device"])
+	}
+
+	for id, payload := range map[string]string{"run": "run", "cancel": "cancel"} {
+		var button map[string]any
+		if err := json.Unmarshal([]byte(configs["homeassistant/button/bazarr-sync_test/"+id+"/config"]), &button); err != nil {
+			t.Fatalf("%s button: %v", id, err)
+		}
+		if button["command_topic"] != "bazarr-sync/command" || button["payload_press"] != payload {
+			t.Errorf("%s button sends %v to %v, want %s to bazarr-sync/command", id, button["payload_press"], button["command_topic"], payload)
+		}
+		if _, found := button["state_topic"]; found {
+			t.Errorf("%s button has a state topic", id)
+		}
+	}
+}
+
+func toStrings(v any) []string {
+	values, _ := v.([]any)
+	var s []string
+	for _, value := range values {
+		str, _ := value.(string)
+		s = append(s, str)
+	}
+	return s
+}
+
+func TestStateIsRetained(t *testing.T) {
+	broker, client, _ := connect(t)
+
+	waitFor(t, "the initial state", func() bool {
+		state, ok := retainedState(broker)
+		return ok && state.State == "idle"
+	})
+	if online := broker.Retained("bazarr-sync/availability")["bazarr-sync/availability"]; online != "online" {
+		t.Errorf("availability = %q, want online", online)
+	}
+
+	client.Update(func(state *mqtt.State) {
+		state.State = "running"
+		state.RunId = "run-1"
+		state.Current = "Inception"
+		state.Progress = 50
+	})
+	waitFor(t, "the running state", func() bool {
+		state, ok := retainedState(broker)
+		return ok && state.State == "running" && state.RunId == "run-1" && state.Current == "Inception" && state.Progress == 50
+	})
+
+	client.Close()
+	waitFor(t, "the device to go offline", func() bool {
+		return broker.Retained("bazarr-sync/availability")["bazarr-sync/availability"] == "offline"
+	})
+}
+
+func TestCommands(t *testing.T) {
+	broker, _, commands := connect(t)
+
+	var received []string
+	for _, payload := range []string{"run", "run full-sync", "cancel"} {
+		if err := broker.Publish("bazarr-sync/command", payload); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case command := <-commands:
+			received = append(received, command)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("command %q not received", payload)
+		}
+	}
+
+	want := []mqtt.Command{{Name: "run"}, {Name: "run", Job: "full-sync"}, {Name: "cancel"}}
+	for i, payload := range received {
+		command, ok := mqtt.ParseCommand(payload)
+		if !ok || command != want[i] {
+			t.Errorf("command %q parsed as %+v, want %+v", payload, command, want[i])
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package mqtttest runs an MQTT broker inside the test process.
+package mqtttest
+
+import (
+	"io"
+	"log/slog"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+)
+
+// Broker is an MQTT broker on a local port that accepts every client.
+type Broker struct {
+	// Address to connect to, for example "tcp://127.0.0.1:41234"
+	URL string
+
+	server *mochi.Server
+}
+
+// NewBroker starts a broker. Close stops it.
+func NewBroker() (*Broker, error) {
+	server := mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := server.AddHook(new(auth.AllowHook), nil); err != nil {
+		return nil, err
+	}
+	tcp := listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})
+	if err := server.AddListener(tcp); err != nil {
+		return nil, err
+	}
+	if err := server.Serve(); err != nil {
+		return nil, err
+	}
+	return &Broker{URL: "tcp://" + tcp.Address(), server: server}, nil
+}
+
+// Publish sends a message as another client would.
+func (b *Broker) Publish(topic string, payload string) error {
+	return b.server.Publish(topic, []byte(payload), false, 1)
+}
+
+// Subscribed reports whether a client subscribed to a filter matching topic.
+func (b *Broker) Subscribed(topic string) bool {
+	return len(b.server.Topics.Subscribers(topic).Subscriptions) > 0
+}
+
+// Retained returns the payloads of the retained messages matching filter, by
+// topic.
+func (b *Broker) Retained(filter string) map[string]string {
+	retained := make(map[string]string)
+	for _, pk := range b.server.Topics.Messages(filter) {
+		retained[pk.TopicName] = string(pk.Payload)
+	}
+	return retained
+}
+
+// Close disconnects the clients and stops the broker.
+func (b *Broker) Close() error {
+	return b.server.Close()
+}
@@ -0,0 +1,216 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+)
+
+const defaultEmailTemplate = `bazarr-sync {{if .Job}}{{.Job}}{{else}}{{.Trigger}}{{end}} run {{.Status}} at {{.Finished.Format "2006-01-02 15:04 MST"}} after {{.Duration}}.
+
+{{.Summary.Success}} synced, {{.Summary.AlreadySynced}} already in sync, {{.Summary.Skipped}} skipped, {{.Summary.Failed}} failed
+{{- range .Parts}}{{if .Error}}
+{{.Name}} did not complete: {{.Error}}{{end}}{{end}}
+{{- if .Synced}}
+
+Newly synced:
+{{- range .SyncedByMedia}}
+{{.Media}}
+{{- range .Subtitles}}
+  - {{.Title}} ({{.Language}}){{end}}{{end}}{{end}}
+{{- if .Failures}}
+
+Failed:
+{{- range .Failures}}
+- {{.Title}} ({{.Language}}): {{.Message}}
+  {{.Path}}{{end}}{{end}}
+{{- if .Quarantined}}
+
+Quarantined after failing in {{.QuarantineAfter}} runs in a row:
+{{- range .Quarantined}}
+- {{.Title}} ({{.Language}})
+  {{.Path}}{{end}}{{end}}
+{{- if .NextRun}}
+
+Next scheduled run: {{.NextRun.Format "2006-01-02 15:04 MST"}}{{end}}`
+
+var emailHtml = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #222;">
+<h2>{{.Title}}</h2>
+{{with .Report}}
+<p>{{if .Job}}{{.Job}}{{else}}{{.Trigger}}{{end}} run <b>{{.Status}}</b> at {{.Finished.Format "2006-01-02 15:04 MST"}} after {{.Duration}}.</p>
+<table cellpadding="6" style="border-collapse: collapse;">
+<tr><th align="left"></th><th>Synced</th><th>Already in sync</th><th>Skipped</th><th>Failed</th></tr>
+{{range .Parts}}<tr><td>{{.Name}}</td><td align="right">{{.Summary.Success}}</td><td align="right">{{.Summary.AlreadySynced}}</td><td align="right">{{.Summary.Skipped}}</td><td align="right">{{.Summary.Failed}}</td></tr>
+{{if .Error}}<tr><td></td><td colspan="4" style="color: #b00;">did not complete: {{.Error}}</td></tr>
+{{end}}{{end}}<tr style="border-top: 1px solid #999;"><td><b>total</b></td><td align="right"><b>{{.Summary.Success}}</b></td><td align="right"><b>{{.Summary.AlreadySynced}}</b></td><td align="right"><b>{{.Summary.Skipped}}</b></td><td align="right"><b>{{.Summary.Failed}}</b></td></tr>
+</table>
+{{if .Synced}}
+<h3>Newly synced</h3>
+{{range .SyncedByMedia}}<p><b>{{.Media}}</b></p>
+<ul>{{range .Subtitles}}<li>{{.Title}} ({{.Language}})</li>{{end}}</ul>
+{{end}}{{end}}
+{{if .Failures}}
+<h3>Failed</h3>
+<ul>{{range .Failures}}<li>{{.Title}} ({{.Language}}): <span style="color: #b00;">{{.Message}}</span><br><small>{{.Path}}</small></li>{{end}}</ul>
+{{end}}
+{{if .Quarantined}}
+<h3>Quarantined after failing in {{.QuarantineAfter}} runs in a row</h3>
+<ul>{{range .Quarantined}}<li>{{.Title}} ({{.Language}})<br><small>{{.Path}}</small></li>{{end}}</ul>
+{{end}}
+{{if .NextRun}}<p>Next scheduled run: {{.NextRun.Format "2006-01-02 15:04 MST"}}</p>{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// Certificates trusted for mail servers; nil trusts the system's
+var smtpRootCAs *x509.CertPool
+
+// sendEmail mails the report. The plain-text part is the rendered message;
+// the HTML part is the built-in report and is left out when the target has
+// its own template.
+func sendEmail(ctx context.Context, target config.NotificationConfig, report Report, subject string, text string) error {
+	var html string
+	if target.Template == "" {
+		var out strings.Builder
+		err := emailHtml.Execute(&out, struct {
+			Title  string
+			Report Report
+		}{subject, report})
+		if err != nil {
+			return fmt.Errorf("HTML report: %w", err)
+		}
+		html = out.String()
+	}
+
+	msg, err := buildEmail(target.Smtp, subject, text, html)
+	if err != nil {
+		return err
+	}
+	return deliver(ctx, target.Smtp, msg)
+}
+
+// buildEmail assembles a MIME message with a plain-text part and, when html
+// is not empty, an HTML alternative.
+func buildEmail(cfg config.SmtpConfig, subject string, text string, html string) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct{ contentType, content string }{{"text/plain", text}}
+	if html != "" {
+		parts = append(parts, struct{ contentType, content string }{"text/html", html})
+	}
+	for _, part := range parts {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		qp.Write([]byte(strings.ReplaceAll(part.content, "\n", "\r\n")))
+		qp.Close()
+	}
+	mw.Close()
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// deliver sends a message through the configured mail server. Without an
+// explicit port, 465 is used for TLS, 25 for no encryption and 587 for
+// STARTTLS, which is the default and required when chosen.
+func deliver(ctx context.Context, cfg config.SmtpConfig, msg []byte) error {
+	encryption := cfg.Encryption
+	if encryption == "" {
+		encryption = "starttls"
+	}
+	port := cfg.Port
+	if port == 0 {
+		switch encryption {
+		case "tls":
+			port = 465
+		case "none":
+			port = 25
+		default:
+			port = 587
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(cfg.Host, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tlsConfig := &tls.Config{ServerName: cfg.Host, RootCAs: smtpRootCAs}
+	if encryption == "tls" {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if encryption == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS", cfg.Host)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, to := range cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("%s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
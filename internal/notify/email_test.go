@@ -0,0 +1,328 @@
+package notify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+)
+
+// receivedMail is a message as the fake mail server received it.
+type receivedMail struct {
+	from   string
+	to     []string
+	data   []byte
+	secure bool
+	auth   string
+}
+
+// smtpServer is a mail server that accepts everything, with STARTTLS when
+// it has a certificate.
+type smtpServer struct {
+	net.Listener
+	tls *tls.Config
+
+	mu    sync.Mutex
+	mails []receivedMail
+}
+
+func newSmtpServer(t *testing.T, tlsConfig *tls.Config) *smtpServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{Listener: ln, tls: tlsConfig}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// config returns the mail settings to send to the server.
+func (s *smtpServer) config(encryption string) config.SmtpConfig {
+	return config.SmtpConfig{
+		Host:       "127.0.0.1",
+		Port:       s.Addr().(*net.TCPAddr).Port,
+		Encryption: encryption,
+		From:       "bazarr-sync@example.com",
+		To:         []string{"alice@example.com", "bob@example.com"},
+	}
+}
+
+func (s *smtpServer) received() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.mails)
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake ESMTP")
+
+	var m receivedMail
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-fake")
+			if s.tls != nil && !m.secure {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			if s.tls == nil {
+				tp.PrintfLine("502 not supported")
+				continue
+			}
+			tp.PrintfLine("220 ready")
+			conn = tls.Server(conn, s.tls)
+			tp = textproto.NewConn(conn)
+			m.secure = true
+		case "AUTH":
+			_, initial, _ := strings.Cut(arg, " ")
+			auth, _ := base64.StdEncoding.DecodeString(initial)
+			m.auth = string(auth)
+			tp.PrintfLine("235 accepted")
+		case "MAIL":
+			m.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			m.to = append(m.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			m.data = data
+			s.mu.Lock()
+			s.mails = append(s.mails, m)
+			s.mu.Unlock()
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+// selfSigned returns a server configuration with a certificate for
+// 127.0.0.1 and trusts it for mail servers until the test ends.
+func selfSigned(t *testing.T) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	roots := smtpRootCAs
+	t.Cleanup(func() { smtpRootCAs = roots })
+	smtpRootCAs = pool
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+}
+
+func sendTestEmail(t *testing.T, cfg config.SmtpConfig) error {
+	t.Helper()
+	msg, err := buildEmail(cfg, "bazarr-sync run failed", "1 failed", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return deliver(ctx, cfg, msg)
+}
+
+func TestDeliverWithoutEncryption(t *testing.T) {
+	srv := newSmtpServer(t, nil)
+	cfg := srv.config("none")
+	if err := sendTestEmail(t, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	mails := srv.received()
+	if len(mails) != 1 {
+		t.Fatalf("received %d mails, want 1", len(mails))
+	}
+	m := mails[0]
+	if m.from != cfg.From || !slices.Equal(m.to, cfg.To) {
+		t.Errorf("mail from %s to %v, want from %s to %v", m.from, m.to, cfg.From, cfg.To)
+	}
+	if m.secure || m.auth != "" {
+		t.Errorf("mail sent with STARTTLS or authentication, want neither")
+	}
+	header, err := mail.ReadMessage(strings.NewReader(string(m.data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to := header.Header.Get("To"); to != "alice@example.com, bob@example.com" {
+		t.Errorf("To = %q, want both recipients", to)
+	}
+}
+
+func TestDeliverStartTLS(t *testing.T) {
+	srv := newSmtpServer(t, selfSigned(t))
+	cfg := srv.config("starttls")
+	cfg.Username = "user"
+	cfg.Password = "secret"
+	if err := sendTestEmail(t, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	mails := srv.received()
+	if len(mails) != 1 {
+		t.Fatalf("received %d mails, want 1", len(mails))
+	}
+	if !mails[0].secure {
+		t.Error("mail sent before STARTTLS")
+	}
+	if mails[0].auth != "\x00user\x00secret" {
+		t.Errorf("authenticated with %q, want user and secret", mails[0].auth)
+	}
+	if !slices.Equal(mails[0].to, cfg.To) {
+		t.Errorf("mail to %v, want %v", mails[0].to, cfg.To)
+	}
+}
+
+func TestDeliverRequiresStartTLS(t *testing.T) {
+	srv := newSmtpServer(t, nil)
+	if err := sendTestEmail(t, srv.config("starttls")); err == nil {
+		t.Fatal("mail sent to a server without STARTTLS")
+	}
+	if mails := srv.received(); len(mails) != 0 {
+		t.Errorf("received %d mails, want none", len(mails))
+	}
+}
+
+func TestSendEmailTextAndHtml(t *testing.T) {
+	srv := newSmtpServer(t, nil)
+	target := config.NotificationConfig{Smtp: srv.config("none")}
+	report := Report{
+		Trigger:  "schedule",
+		Status:   "failed",
+		Finished: time.Now(),
+		Summary:  Summary{Success: 1, Failed: 1},
+		Failures: []Failure{{Subtitle: Subtitle{Title: "Dark S01E02", Language: "en"}, Message: "Internal error"}},
+	}
+	text := "1 synced, 1 failed\nDark S01E02 (en): Internal error"
+	if err := sendEmail(context.Background(), target, report, "bazarr-sync run failed", text); err != nil {
+		t.Fatal(err)
+	}
+
+	mails := srv.received()
+	if len(mails) != 1 {
+		t.Fatalf("received %d mails, want 1", len(mails))
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(mails[0].data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != "bazarr-sync run failed" {
+		t.Errorf("Subject = %q", subject)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	var types []string
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType := part.Header.Get("Content-Type")
+		types = append(types, contentType)
+		switch {
+		case strings.HasPrefix(contentType, "text/plain"):
+			if got := strings.ReplaceAll(string(content), "\r\n", "\n"); got != text {
+				t.Errorf("text part = %q, want %q", got, text)
+			}
+		case strings.HasPrefix(contentType, "text/html"):
+			for _, want := range []string{"<h2>bazarr-sync run failed</h2>", "<b>failed</b>", "Dark S01E02 (en)"} {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("HTML part does not contain %q", want)
+				}
+			}
+		}
+	}
+	want := []string{"text/plain; charset=utf-8", "text/html; charset=utf-8"}
+	if !slices.Equal(types, want) {
+		t.Errorf("parts = %v, want %v", types, want)
+	}
+}
+
+// A custom template replaces the plain text, so the built-in HTML report is
+// left out.
+func TestSendEmailTemplateIsTextOnly(t *testing.T) {
+	srv := newSmtpServer(t, nil)
+	target := config.NotificationConfig{Smtp: srv.config("none"), Template: "{{.Status}}"}
+	if err := sendEmail(context.Background(), target, Report{Status: "failed"}, "bazarr-sync", "failed"); err != nil {
+		t.Fatal(err)
+	}
+	mails := srv.received()
+	if len(mails) != 1 {
+		t.Fatalf("received %d mails, want 1", len(mails))
+	}
+	if data := string(mails[0].data); strings.Contains(data, "text/html") || !strings.Contains(data, "text/plain") {
+		t.Errorf("message is not text only:\n%s", data)
+	}
+}
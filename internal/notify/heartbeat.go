@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+)
+
+// Signals a heartbeat ping reports
+const (
+	PingStart   = "start"
+	PingSuccess = "success"
+	PingFail    = "fail"
+)
+
+// Uptime Kuma shows the message of a push on the monitor, so it is kept short
+const uptimeKumaMessageLength = 200
+
+// Ping reports the state of a scheduled job to a dead man's switch. message
+// is a short summary that is sent along where the service supports it.
+// Uptime Kuma has no start signal, so starts are only sent to it when a
+// StartUrl is configured.
+func Ping(ctx context.Context, hb config.HeartbeatConfig, signal string, message string) error {
+	method, target, body := pingRequest(hb, signal, message)
+	if target == "" {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(text)))
+	}
+	return nil
+}
+
+// pingRequest returns the method, URL and body of a ping, or an empty URL
+// when the signal is not sent.
+func pingRequest(hb config.HeartbeatConfig, signal string, message string) (string, string, string) {
+	if hb.Type == "uptime-kuma" {
+		target := hb.Url
+		status := "up"
+		switch signal {
+		case PingStart:
+			return "GET", hb.StartUrl, ""
+		case PingFail:
+			status = "down"
+			if hb.FailUrl != "" {
+				target = hb.FailUrl
+			}
+		}
+		u, err := url.Parse(target)
+		if err != nil {
+			return "GET", target, ""
+		}
+		query := u.Query()
+		query.Set("status", status)
+		query.Set("msg", truncate(strings.ReplaceAll(message, "\n", "; "), uptimeKumaMessageLength))
+		u.RawQuery = query.Encode()
+		return "GET", u.String(), ""
+	}
+
+	// healthchecks.io: the check URL reports success, /start and /fail the
+	// other signals. A POST body is shown with the ping.
+	base := strings.TrimSuffix(hb.Url, "/")
+	switch signal {
+	case PingStart:
+		if hb.StartUrl != "" {
+			return "POST", hb.StartUrl, ""
+		}
+		return "POST", base + "/start", ""
+	case PingFail:
+		if hb.FailUrl != "" {
+			return "POST", hb.FailUrl, message
+		}
+		return "POST", base + "/fail", message
+	}
+	return "POST", hb.Url, message
+}
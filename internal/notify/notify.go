@@ -0,0 +1,352 @@
+// Package notify sends the result of a sync run to the chat services,
+// webhooks and mailboxes configured in the Notifications section.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/config"
+)
+
+// Events a target can subscribe to
+const (
+	EventRunFinished = "run_finished" // every run, whatever its outcome
+	EventRunFailed   = "run_failed"   // a part of the run could not complete
+	EventFailures    = "failures"     // subtitles failed to sync
+	EventQuarantined = "quarantined"  // subtitles failed in QuarantineAfter runs in a row for the first time
+)
+
+// Types of targets with a built-in formatter
+const (
+	TypeDiscord = "discord"
+	TypeSlack   = "slack"
+	TypeGotify  = "gotify"
+	TypeNtfy    = "ntfy"
+	TypeWebhook = "webhook"
+	TypeEmail   = "email"
+)
+
+// Events sent to targets that do not list any
+var defaultEvents = []string{EventRunFailed, EventQuarantined, EventFailures}
+
+// Number of failed subtitles listed by the default template
+const topFailures = 10
+
+const defaultTitle = `bazarr-sync: {{if .Job}}{{.Job}}{{else}}{{.Trigger}}{{end}} run {{.Status}}`
+
+const defaultTemplate = `{{.Summary.Success}} synced, {{.Summary.AlreadySynced}} already in sync, {{.Summary.Skipped}} skipped, {{.Summary.Failed}} failed in {{.Duration}}
+{{- range .Parts}}{{if .Error}}
+{{.Name}} did not complete: {{.Error}}{{end}}{{end}}
+{{- if .Failures}}
+
+Failed subtitles:
+{{- range .TopFailures}}
+- {{.Title}} ({{.Language}}): {{.Message}}{{end}}
+{{- if .MoreFailures}}
+...and {{.MoreFailures}} more{{end}}{{end}}
+{{- if .Quarantined}}
+
+Quarantined after failing in {{.QuarantineAfter}} runs in a row:
+{{- range .Quarantined}}
+- {{.Title}} ({{.Language}}){{end}}{{end}}`
+
+// Summary counts the subtitles of a run or part.
+type Summary struct {
+	Success       int `json:"success"`
+	AlreadySynced int `json:"already_synced"`
+	Skipped       int `json:"skipped"`
+	Failed        int `json:"failed"`
+}
+
+// Part is one pass of a run, such as all movies.
+type Part struct {
+	Name    string  `json:"name"`
+	Summary Summary `json:"summary"`
+	// Why the part did not complete, empty when it did
+	Error string `json:"error,omitempty"`
+}
+
+// Subtitle is a subtitle synced during the run.
+type Subtitle struct {
+	Kind     string `json:"kind"`
+	Id       int    `json:"id"`
+	Title    string `json:"title"`
+	Media    string `json:"media,omitempty"` // movie or series title
+	Language string `json:"language"`
+	Path     string `json:"path"`
+}
+
+// Failure is a subtitle that failed to sync.
+type Failure struct {
+	Subtitle
+	Message string `json:"message"`
+}
+
+// MediaGroup is the subtitles of one movie or series.
+type MediaGroup struct {
+	Media     string
+	Subtitles []Subtitle
+}
+
+// Report is the result of a run. It is the data passed to the templates.
+type Report struct {
+	Event    string        `json:"event"`
+	RunId    string        `json:"run_id"`
+	Trigger  string        `json:"trigger"`
+	Job      string        `json:"job,omitempty"`
+	Status   string        `json:"status"` // completed, failed or cancelled
+	Started  time.Time     `json:"started"`
+	Finished time.Time     `json:"finished"`
+	Duration time.Duration `json:"duration"`
+	Summary  Summary       `json:"summary"`
+	Parts    []Part        `json:"parts"`
+	Synced   []Subtitle    `json:"synced"`
+	Failures []Failure     `json:"failures"`
+	// Failures of this run that made their subtitle quarantined, having
+	// failed in QuarantineAfter runs in a row
+	Quarantined     []Failure `json:"quarantined"`
+	QuarantineAfter int       `json:"quarantine_after,omitempty"`
+	// Next run of the scheduler, nil outside scheduler mode
+	NextRun *time.Time `json:"next_run,omitempty"`
+}
+
+// SyncedByMedia groups the synced subtitles by movie or series, sorted by
+// title.
+func (r Report) SyncedByMedia() []MediaGroup {
+	var groups []MediaGroup
+	index := make(map[string]int)
+	for _, subtitle := range r.Synced {
+		media := subtitle.Media
+		if media == "" {
+			media = subtitle.Title
+		}
+		i, found := index[media]
+		if !found {
+			i = len(groups)
+			index[media] = i
+			groups = append(groups, MediaGroup{Media: media})
+		}
+		groups[i].Subtitles = append(groups[i].Subtitles, subtitle)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].Media) < strings.ToLower(groups[j].Media)
+	})
+	return groups
+}
+
+// TopFailures returns the first failures, for messages that have to stay short.
+func (r Report) TopFailures() []Failure {
+	if len(r.Failures) > topFailures {
+		return r.Failures[:topFailures]
+	}
+	return r.Failures
+}
+
+// MoreFailures returns how many failures TopFailures leaves out.
+func (r Report) MoreFailures() int {
+	return len(r.Failures) - len(r.TopFailures())
+}
+
+// Event picks the event a target is notified of for a report. A run is only
+// announced once per target, as the most important event that target
+// listens to. It returns false when the target is not interested.
+func Event(target config.NotificationConfig, report Report) (string, bool) {
+	if len(target.Triggers) > 0 && !contains(target.Triggers, report.Trigger) {
+		return "", false
+	}
+
+	events := target.Events
+	if len(events) == 0 {
+		events = defaultEvents
+	}
+	wants := func(event string) bool {
+		return contains(events, event)
+	}
+
+	threshold := target.FailureThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+	switch {
+	case report.Status == "failed" && wants(EventRunFailed):
+		return EventRunFailed, true
+	case len(report.Quarantined) > 0 && wants(EventQuarantined):
+		return EventQuarantined, true
+	case report.Summary.Failed >= threshold && wants(EventFailures):
+		return EventFailures, true
+	case wants(EventRunFinished):
+		return EventRunFinished, true
+	}
+	return "", false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Name returns how a target is referred to in logs.
+func Name(target config.NotificationConfig) string {
+	if target.Name != "" {
+		return target.Name
+	}
+	return target.Type
+}
+
+// Render fills in the title and message templates of a target.
+func Render(target config.NotificationConfig, report Report) (string, string, error) {
+	titleTemplate, messageTemplate := target.Title, target.Template
+	if titleTemplate == "" {
+		titleTemplate = defaultTitle
+	}
+	if messageTemplate == "" {
+		messageTemplate = defaultTemplate
+		if target.Type == TypeEmail {
+			messageTemplate = defaultEmailTemplate
+		}
+	}
+
+	title, err := execute("title", titleTemplate, report)
+	if err != nil {
+		return "", "", err
+	}
+	message, err := execute("message", messageTemplate, report)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(title), strings.TrimSpace(message), nil
+}
+
+func execute(name string, text string, report Report) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s template: %w", name, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, report); err != nil {
+		return "", fmt.Errorf("%s template: %w", name, err)
+	}
+	return out.String(), nil
+}
+
+// Send renders the report and delivers it to the target.
+func Send(ctx context.Context, target config.NotificationConfig, report Report) error {
+	title, message, err := Render(target, report)
+	if err != nil {
+		return err
+	}
+
+	if target.Type == TypeEmail {
+		return sendEmail(ctx, target, report, title, message)
+	}
+
+	var req *http.Request
+	switch target.Type {
+	case TypeDiscord:
+		req, err = jsonRequest(ctx, target.Url, map[string]string{
+			"content": truncate("**"+title+"**\n"+message, 2000),
+		})
+	case TypeSlack:
+		req, err = jsonRequest(ctx, target.Url, map[string]string{
+			"text": "*" + title + "*\n" + message,
+		})
+	case TypeGotify:
+		req, err = jsonRequest(ctx, strings.TrimSuffix(target.Url, "/")+"/message", map[string]any{
+			"title":    title,
+			"message":  message,
+			"priority": gotifyPriority(report.Event),
+		})
+		if err == nil {
+			req.Header.Set("X-Gotify-Key", target.Token)
+		}
+	case TypeNtfy:
+		req, err = http.NewRequestWithContext(ctx, "POST", target.Url, strings.NewReader(message))
+		if err == nil {
+			req.Header.Set("Title", title)
+			if report.Event == EventRunFinished {
+				req.Header.Set("Priority", "default")
+				req.Header.Set("Tags", "white_check_mark")
+			} else {
+				req.Header.Set("Priority", "high")
+				req.Header.Set("Tags", "warning")
+			}
+			setBearer(req, target.Token)
+		}
+	case TypeWebhook:
+		req, err = jsonRequest(ctx, target.Url, map[string]any{
+			"event":   report.Event,
+			"title":   title,
+			"message": message,
+			"run":     report,
+		})
+		if err == nil {
+			setBearer(req, target.Token)
+		}
+	default:
+		return fmt.Errorf("unknown notification type %q", target.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func jsonRequest(ctx context.Context, url string, payload any) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func setBearer(req *http.Request, token string) {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// Gotify shows priorities from 8 as urgent
+func gotifyPriority(event string) int {
+	if event == EventRunFinished {
+		return 5
+	}
+	return 8
+}
+
+// truncate shortens s to at most max characters, for services that reject
+// longer messages.
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,62 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/history"
+)
+
+// CSV reports have one row per line of the other formats. The section
+// column tells the rows apart, so a spreadsheet can filter on it: run,
+// part, series, movie, language, failed and skipped.
+var csvHeader = []string{"section", "name", "language", "success", "already_synced", "skipped", "failed",
+	"duration_seconds", "path", "message"}
+
+func writeCSV(w io.Writer, r Report) error {
+	out := csv.NewWriter(w)
+	out.Write(csvHeader)
+
+	counts := func(section string, name string, s history.Summary, d time.Duration, message string) {
+		out.Write([]string{section, name, "", strconv.Itoa(s.Success), strconv.Itoa(s.AlreadySynced),
+			strconv.Itoa(s.Skipped), strconv.Itoa(s.Failed), formatSeconds(d), "", message})
+	}
+
+	status := fmt.Sprintf("%s run %s", r.Trigger, r.Status)
+	if r.Job != "" {
+		status = fmt.Sprintf("%s run %s", r.Job, r.Status)
+	}
+	counts("run", r.Id, r.Summary, r.Duration(), status)
+	for _, part := range r.Parts {
+		counts("part", part.Name, part.Summary, part.Duration(), part.Error)
+	}
+	for _, t := range r.Series {
+		counts("series", t.Name, t.Summary, t.Duration, "")
+	}
+	for _, t := range r.Movies {
+		counts("movie", t.Name, t.Summary, t.Duration, "")
+	}
+	for _, t := range r.Languages {
+		out.Write([]string{"language", t.Name, t.Name, strconv.Itoa(t.Success), strconv.Itoa(t.AlreadySynced),
+			strconv.Itoa(t.Skipped), strconv.Itoa(t.Failed), formatSeconds(t.Duration), "", ""})
+	}
+
+	subtitles := func(section string, subs []history.Subtitle) {
+		for _, sub := range subs {
+			out.Write([]string{section, sub.Title, sub.Language, "", "", "", "",
+				formatSeconds(subtitleDuration(sub)), sub.Path, sub.Message})
+		}
+	}
+	subtitles("failed", r.Failed)
+	subtitles("skipped", r.Skipped)
+
+	out.Flush()
+	return out.Error()
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 1, 64)
+}
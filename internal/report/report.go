@@ -0,0 +1,169 @@
+// Package report writes the result of a run as a file meant to be kept or
+// shared: HTML, Markdown or CSV, chosen by the file extension.
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/history"
+)
+
+// Report formats
+const (
+	HTML     = "html"
+	Markdown = "md"
+	CSV      = "csv"
+)
+
+// Format returns the report format for a file name. The config package
+// checks Report.File against the same extensions.
+func Format(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return HTML, nil
+	case ".md", ".markdown":
+		return Markdown, nil
+	case ".csv":
+		return CSV, nil
+	}
+	return "", fmt.Errorf("report file must end in .html, .md or .csv, got %q", path)
+}
+
+// Totals are the subtitle counts of a movie, series or language, and the time
+// spent syncing them.
+type Totals struct {
+	Name string
+	history.Summary
+	Duration time.Duration
+}
+
+// Report is the content of a report.
+type Report struct {
+	history.Run
+	Series    []Totals
+	Movies    []Totals
+	Languages []Totals
+	Failed    []history.Subtitle
+	Skipped   []history.Subtitle
+}
+
+// New breaks a run down by series, movie and language.
+func New(run history.Run) Report {
+	r := Report{Run: run}
+	series := make(map[string]*Totals)
+	movies := make(map[string]*Totals)
+	languages := make(map[string]*Totals)
+
+	for _, sub := range run.Subtitles {
+		media := movies
+		if sub.Kind == "episode" {
+			media = series
+		}
+		name := sub.Media
+		if name == "" {
+			name = sub.Title
+		}
+		add(media, name, sub)
+		if sub.Language != "" {
+			add(languages, sub.Language, sub)
+		}
+
+		switch sub.Outcome {
+		case "failed":
+			r.Failed = append(r.Failed, sub)
+		case "skipped":
+			r.Skipped = append(r.Skipped, sub)
+		}
+	}
+
+	r.Series = sorted(series)
+	r.Movies = sorted(movies)
+	r.Languages = sorted(languages)
+	return r
+}
+
+func add(totals map[string]*Totals, name string, sub history.Subtitle) {
+	t, found := totals[name]
+	if !found {
+		t = &Totals{Name: name}
+		totals[name] = t
+	}
+	switch sub.Outcome {
+	case "success":
+		t.Success++
+	case "already_synced":
+		t.AlreadySynced++
+	case "skipped":
+		t.Skipped++
+	default:
+		t.Failed++
+	}
+	t.Duration += time.Duration(sub.Duration * float64(time.Second))
+}
+
+func sorted(totals map[string]*Totals) []Totals {
+	list := make([]Totals, 0, len(totals))
+	for _, t := range totals {
+		list = append(list, *t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name)
+	})
+	return list
+}
+
+// Write writes the report of a run to path, in the format given by its
+// extension. The file is replaced atomically.
+func Write(path string, run history.Run) error {
+	format, err := Format(path)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".report-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	r := New(run)
+	switch format {
+	case HTML:
+		err = htmlReport.Execute(tmp, r)
+	case Markdown:
+		err = markdownReport.Execute(tmp, r)
+	case CSV:
+		err = writeCSV(tmp, r)
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// seconds formats a duration for a report, rounded to the second or, below
+// that, to the millisecond.
+func seconds(d time.Duration) string {
+	if d >= time.Second {
+		return d.Round(time.Second).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+func subtitleDuration(sub history.Subtitle) time.Duration {
+	return time.Duration(sub.Duration * float64(time.Second))
+}
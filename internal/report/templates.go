@@ -0,0 +1,119 @@
+package report
+
+import (
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/history"
+)
+
+var funcs = map[string]any{
+	"duration": seconds,
+	"subtitleDuration": func(sub history.Subtitle) string {
+		if sub.Duration == 0 {
+			return ""
+		}
+		return seconds(subtitleDuration(sub))
+	},
+	"time": func(t time.Time) string {
+		return t.Local().Format("2006-01-02 15:04:05 MST")
+	},
+	// Markdown table cells end at a pipe or a line break
+	"cell": func(s string) string {
+		return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+	},
+}
+
+var htmlReport = htmltemplate.Must(htmltemplate.New("report").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>bazarr-sync run {{.Id}}</title>
+<style>
+body { font-family: sans-serif; color: #222; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { padding: 4px 10px; border-bottom: 1px solid #ddd; text-align: left; }
+td.n { text-align: right; }
+tr.total td { font-weight: bold; border-top: 1px solid #999; }
+.error { color: #b00; }
+small { color: #666; }
+</style>
+</head>
+<body>
+<h1>bazarr-sync run {{.Id}}</h1>
+<p>{{if .Job}}{{.Job}}{{else}}{{.Trigger}}{{end}} run <b>{{.Status}}</b>, started {{time .Started}}, finished {{time .Finished}} after {{duration .Duration}}.{{if .RetryOf}} Retry of run {{.RetryOf}}.{{end}}</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th></th><th>Synced</th><th>Already in sync</th><th>Skipped</th><th>Failed</th><th>Duration</th></tr>
+{{range .Parts}}<tr><td>{{.Name}}</td><td class="n">{{.Summary.Success}}</td><td class="n">{{.Summary.AlreadySynced}}</td><td class="n">{{.Summary.Skipped}}</td><td class="n">{{.Summary.Failed}}</td><td class="n">{{duration .Duration}}</td></tr>
+{{if .Error}}<tr><td></td><td colspan="5" class="error">did not complete: {{.Error}}</td></tr>
+{{end}}{{end}}<tr class="total"><td>total</td><td class="n">{{.Summary.Success}}</td><td class="n">{{.Summary.AlreadySynced}}</td><td class="n">{{.Summary.Skipped}}</td><td class="n">{{.Summary.Failed}}</td><td class="n">{{duration .Duration}}</td></tr>
+</table>
+{{define "totals"}}<table>
+<tr><th></th><th>Synced</th><th>Already in sync</th><th>Skipped</th><th>Failed</th><th>Sync time</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td class="n">{{.Success}}</td><td class="n">{{.AlreadySynced}}</td><td class="n">{{.Skipped}}</td><td class="n">{{.Failed}}</td><td class="n">{{duration .Duration}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Series}}<h2>Series</h2>
+{{template "totals" .Series}}{{end}}
+{{if .Movies}}<h2>Movies</h2>
+{{template "totals" .Movies}}{{end}}
+{{if .Languages}}<h2>Languages</h2>
+{{template "totals" .Languages}}{{end}}
+{{if .Failed}}<h2>Failed</h2>
+<table>
+<tr><th>Subtitle</th><th>Language</th><th>Error</th><th>Sync time</th></tr>
+{{range .Failed}}<tr><td>{{.Title}}<br><small>{{.Path}}</small></td><td>{{.Language}}</td><td class="error">{{.Message}}</td><td class="n">{{subtitleDuration .}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Skipped}}<h2>Skipped</h2>
+<table>
+<tr><th>Subtitle</th><th>Language</th><th>Reason</th></tr>
+{{range .Skipped}}<tr><td>{{.Title}}{{if .Path}}<br><small>{{.Path}}</small>{{end}}</td><td>{{.Language}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+var markdownReport = template.Must(template.New("report").Funcs(funcs).Parse(`# bazarr-sync run {{.Id}}
+
+{{if .Job}}{{.Job}}{{else}}{{.Trigger}}{{end}} run **{{.Status}}**, started {{time .Started}}, finished {{time .Finished}} after {{duration .Duration}}.{{if .RetryOf}} Retry of run {{.RetryOf}}.{{end}}
+
+## Summary
+
+| | Synced | Already in sync | Skipped | Failed | Duration |
+|---|---:|---:|---:|---:|---:|
+{{range .Parts}}| {{.Name}}{{if .Error}} (did not complete: {{cell .Error}}){{end}} | {{.Summary.Success}} | {{.Summary.AlreadySynced}} | {{.Summary.Skipped}} | {{.Summary.Failed}} | {{duration .Duration}} |
+{{end}}| **total** | **{{.Summary.Success}}** | **{{.Summary.AlreadySynced}}** | **{{.Summary.Skipped}}** | **{{.Summary.Failed}}** | **{{duration .Duration}}** |
+{{define "totals"}}
+| | Synced | Already in sync | Skipped | Failed | Sync time |
+|---|---:|---:|---:|---:|---:|
+{{range .}}| {{cell .Name}} | {{.Success}} | {{.AlreadySynced}} | {{.Skipped}} | {{.Failed}} | {{duration .Duration}} |
+{{end}}{{end}}
+{{- if .Series}}
+## Series
+{{template "totals" .Series}}{{end}}
+{{- if .Movies}}
+## Movies
+{{template "totals" .Movies}}{{end}}
+{{- if .Languages}}
+## Languages
+{{template "totals" .Languages}}{{end}}
+{{- if .Failed}}
+## Failed
+
+| Subtitle | Language | Error | Sync time |
+|---|---|---|---:|
+{{range .Failed}}| {{cell .Title}}<br>` + "`{{cell .Path}}`" + ` | {{.Language}} | {{cell .Message}} | {{subtitleDuration .}} |
+{{end}}{{end}}
+{{- if .Skipped}}
+## Skipped
+
+| Subtitle | Language | Reason |
+|---|---|---|
+{{range .Skipped}}| {{cell .Title}} | {{.Language}} | {{cell .Message}} |
+{{end}}{{end}}`))
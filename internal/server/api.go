@@ -0,0 +1,257 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrUnknownJob is returned by a Controller for a job name that is not scheduled.
+	ErrUnknownJob = errors.New("unknown job")
+	// ErrNotRunning is returned by a Controller when there is no run to act on.
+	ErrNotRunning = errors.New("no run in progress")
+	// ErrInvalidRequest is returned by a Controller for a sync request it cannot run.
+	ErrInvalidRequest = errors.New("invalid sync request")
+	// ErrUnknownFailure is returned by a Controller for a subtitle that has no recorded failure.
+	ErrUnknownFailure = errors.New("no failure recorded for subtitle")
+)
+
+// Controller is implemented by the scheduler and drives the control API.
+type Controller interface {
+	Jobs() []Job
+	// RunJob queues a scheduled job to run now and returns the run ID.
+	RunJob(name string) (string, error)
+	// StartSync queues an ad-hoc sync and returns the run ID.
+	StartSync(req SyncRequest) (string, error)
+	// Current returns the run in progress.
+	Current() (Run, error)
+	// Cancel stops the run in progress and returns its ID.
+	Cancel() (string, error)
+	// Runs returns queued, running and recently finished runs, newest first.
+	Runs() []Run
+	// Failures returns the subtitles whose last sync failed, newest first.
+	Failures() []Failure
+	// RetryFailure queues a sync of one failed subtitle and returns the run ID.
+	RetryFailure(path string) (string, error)
+	// Cache returns the subtitles recorded as synced.
+	Cache() CacheContents
+	// Imported schedules a sync of the subtitles of newly imported media.
+	Imported(ev ImportEvent) error
+	// Notified schedules a sync of a subtitle Bazarr sent a notification for.
+	Notified(n SubtitleNotification) error
+	// SyncSubtitle queues a sync of a single subtitle and returns the run ID.
+	SyncSubtitle(req SubtitleRequest) (string, error)
+}
+
+type Job struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	Full     bool      `json:"full"`
+	NextRun  time.Time `json:"next_run"`
+	LastRun  time.Time `json:"last_run,omitempty"`
+}
+
+type SyncRequest struct {
+	Movies    bool  `json:"movies"`
+	Shows     bool  `json:"shows"`
+	RadarrIds []int `json:"radarr_ids,omitempty"`
+	SonarrIds []int `json:"sonarr_ids,omitempty"`
+}
+
+// SubtitleRequest names a single subtitle to sync.
+type SubtitleRequest struct {
+	Kind string `json:"kind"` // "movie" or "episode"
+	// Radarr movie ID or Sonarr episode ID
+	Id       int    `json:"id"`
+	SeriesId int    `json:"series_id,omitempty"`
+	Language string `json:"language"`
+	Path     string `json:"path"`
+}
+
+type Summary struct {
+	Success       int `json:"success"`
+	AlreadySynced int `json:"already_synced"`
+	Skipped       int `json:"skipped"`
+	Failed        int `json:"failed"`
+}
+
+type Part struct {
+	Name    string  `json:"name"`
+	Summary Summary `json:"summary"`
+	Error   string  `json:"error,omitempty"`
+}
+
+type Progress struct {
+	Part     string `json:"part"`
+	Position int    `json:"position"`
+	Total    int    `json:"total"`
+	Current  string `json:"current"`
+}
+
+// Run status values
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+type Run struct {
+	Id       string     `json:"id"`
+	Trigger  string     `json:"trigger"`
+	Job      string     `json:"job,omitempty"`
+	Status   string     `json:"status"`
+	Queued   time.Time  `json:"queued"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Summary  Summary    `json:"summary"`
+	Parts    []Part     `json:"parts,omitempty"`
+	Progress *Progress  `json:"progress,omitempty"`
+}
+
+// Failure is a subtitle whose last sync failed.
+type Failure struct {
+	Kind     string    `json:"kind"`
+	Id       int       `json:"id"`
+	Title    string    `json:"title"`
+	Language string    `json:"language"`
+	Path     string    `json:"path"`
+	Message  string    `json:"message"`
+	RunId    string    `json:"run_id"`
+	Time     time.Time `json:"time"`
+}
+
+type RetryRequest struct {
+	Path string `json:"path"`
+}
+
+type CacheContents struct {
+	Enabled bool     `json:"enabled"`
+	Movies  []string `json:"movies"`
+	Shows   []string `json:"shows"`
+}
+
+func (s *Server) routes() {
+	s.HandlePublic("GET /api/openapi.yaml", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openapiDoc)
+	}))
+	s.Handle("GET /api/jobs", http.HandlerFunc(s.listJobs))
+	s.Handle("POST /api/jobs/{name}/run", http.HandlerFunc(s.runJob))
+	s.Handle("POST /api/sync", http.HandlerFunc(s.startSync))
+	s.Handle("POST /api/subtitles", http.HandlerFunc(s.syncSubtitle))
+	s.Handle("GET /api/runs", http.HandlerFunc(s.listRuns))
+	s.Handle("GET /api/runs/current", http.HandlerFunc(s.currentRun))
+	s.Handle("POST /api/runs/current/cancel", http.HandlerFunc(s.cancelRun))
+	s.Handle("GET /api/failures", http.HandlerFunc(s.listFailures))
+	s.Handle("POST /api/failures/retry", http.HandlerFunc(s.retryFailure))
+	s.Handle("GET /api/cache", http.HandlerFunc(s.showCache))
+	s.Handle("GET /api/events", http.HandlerFunc(s.streamEvents))
+	s.routeWebhooks()
+	s.routeNotifications()
+	s.routeDashboard()
+}
+
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.controller.Jobs())
+}
+
+func (s *Server) runJob(w http.ResponseWriter, r *http.Request) {
+	id, err := s.controller.RunJob(r.PathValue("name"))
+	if errors.Is(err, ErrUnknownJob) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"run_id": id})
+}
+
+func (s *Server) startSync(w http.ResponseWriter, r *http.Request) {
+	var req SyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	id, err := s.controller.StartSync(req)
+	if errors.Is(err, ErrInvalidRequest) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"run_id": id})
+}
+
+func (s *Server) syncSubtitle(w http.ResponseWriter, r *http.Request) {
+	var req SubtitleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	id, err := s.controller.SyncSubtitle(req)
+	if errors.Is(err, ErrInvalidRequest) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"run_id": id})
+}
+
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.controller.Runs())
+}
+
+func (s *Server) currentRun(w http.ResponseWriter, r *http.Request) {
+	run, err := s.controller.Current()
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+func (s *Server) cancelRun(w http.ResponseWriter, r *http.Request) {
+	id, err := s.controller.Cancel()
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"run_id": id})
+}
+
+func (s *Server) listFailures(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.controller.Failures())
+}
+
+func (s *Server) retryFailure(w http.ResponseWriter, r *http.Request) {
+	var req RetryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	id, err := s.controller.RetryFailure(req.Path)
+	if errors.Is(err, ErrUnknownFailure) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"run_id": id})
+}
+
+func (s *Server) showCache(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.controller.Cache())
+}
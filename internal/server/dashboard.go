@@ -0,0 +1,20 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// routeDashboard serves the web dashboard. The static files are public; the
+// page asks for the token and sends it with its API requests.
+func (s *Server) routeDashboard() {
+	files, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	s.HandlePublic("GET /", http.FileServerFS(files))
+}
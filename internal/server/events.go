@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Events buffered per subscriber. A client that falls further behind misses
+// events rather than slowing down the sync.
+const subscriberBuffer = 256
+
+// broker fans published events out to the connected event streams.
+type broker struct {
+	mu          sync.Mutex
+	subscribers map[chan []byte]bool
+	closed      chan struct{}
+}
+
+func newBroker() *broker {
+	return &broker{
+		subscribers: make(map[chan []byte]bool),
+		closed:      make(chan struct{}),
+	}
+}
+
+func (b *broker) subscribe() chan []byte {
+	ch := make(chan []byte, subscriberBuffer)
+	b.mu.Lock()
+	b.subscribers[ch] = true
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *broker) unsubscribe(ch chan []byte) {
+	b.mu.Lock()
+	delete(b.subscribers, ch)
+	b.mu.Unlock()
+}
+
+func (b *broker) publish(data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
+
+// close ends all event streams so a shutdown does not wait for them.
+func (b *broker) close() {
+	close(b.closed)
+}
+
+// Publish sends v, encoded as JSON, to every client of GET /api/events.
+func (s *Server) Publish(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	s.events.publish(data)
+}
+
+// streamEvents serves published events as server-sent events until the
+// client goes away or the server shuts down.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	ch := s.events.subscribe()
+	defer s.events.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	// Tell the browser to start listening
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	// Comments keep proxies from closing an idle stream
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case data := <-ch:
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-s.events.closed:
+			return
+		}
+	}
+}
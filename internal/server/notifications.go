@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SubtitleNotification is a subtitle Bazarr reported in a notification.
+type SubtitleNotification struct {
+	Kind  string // "movie" or "episode", as used by the sync API
+	Title string // movie or series title, without the year
+	Year  int    // 0 when the notification has none
+	// Set for episodes
+	Season       int
+	Episode      int
+	EpisodeTitle string
+	// Language name as Bazarr shows it, for example "English"
+	Language string
+	Forced   bool
+	HI       bool
+	// downloaded, manually downloaded, upgraded or uploaded
+	Action string
+}
+
+// Body of an Apprise json:// notification
+type appriseNotification struct {
+	Version string `json:"version"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+var (
+	// "English forced subtitles downloaded from opensubtitles with a score of 95.1%."
+	notificationMessage = regexp.MustCompile(`^(.+?)( forced| HI)? subtitles (downloaded|manually downloaded|upgraded|uploaded)\b`)
+	// "Breaking Bad (2008) - S01E02 - Cat's in the Bag..."
+	notificationEpisode = regexp.MustCompile(`^(.+?)(?: \((\d{4})\))? - S(\d+)E(\d+) - (.*)$`)
+	// "The Matrix (1999)"
+	notificationMovie = regexp.MustCompile(`^(.+?)(?: \((\d{4})\))?$`)
+)
+
+// ParseNotification reads the body of a Bazarr notification, which has the
+// form "<media> : <message>". It returns false for notifications that are
+// not about a new subtitle, such as deletions or tests.
+func ParseNotification(body string) (SubtitleNotification, bool) {
+	media, message, found := cutLast(strings.TrimSpace(body), " : ")
+	if !found {
+		return SubtitleNotification{}, false
+	}
+
+	m := notificationMessage.FindStringSubmatch(message)
+	if m == nil {
+		return SubtitleNotification{}, false
+	}
+	n := SubtitleNotification{
+		Language: m[1],
+		Forced:   m[2] == " forced",
+		HI:       m[2] == " HI",
+		Action:   m[3],
+	}
+
+	if e := notificationEpisode.FindStringSubmatch(media); e != nil {
+		n.Kind = "episode"
+		n.Title = e[1]
+		n.Year, _ = strconv.Atoi(e[2])
+		n.Season, _ = strconv.Atoi(e[3])
+		n.Episode, _ = strconv.Atoi(e[4])
+		n.EpisodeTitle = e[5]
+		return n, true
+	}
+	mv := notificationMovie.FindStringSubmatch(media)
+	n.Kind = "movie"
+	n.Title = mv[1]
+	n.Year, _ = strconv.Atoi(mv[2])
+	return n, true
+}
+
+// cutLast is strings.Cut around the last occurrence of sep, as titles may
+// contain it too.
+func cutLast(s string, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+func (s *Server) routeNotifications() {
+	s.Handle("POST /api/notifications/apprise", http.HandlerFunc(s.appriseNotification))
+}
+
+// appriseNotification accepts the notifications Bazarr sends through an
+// Apprise json:// target.
+func (s *Server) appriseNotification(w http.ResponseWriter, r *http.Request) {
+	var payload appriseNotification
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+
+	n, ok := ParseNotification(payload.Message)
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+		return
+	}
+
+	err := s.controller.Notified(n)
+	if errors.Is(err, ErrInvalidRequest) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "scheduled"})
+}
@@ -0,0 +1,68 @@
+package server
+
+import "testing"
+
+func TestParseNotification(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want SubtitleNotification
+		ok   bool
+	}{
+		{
+			name: "movie",
+			body: "The Matrix (1999) : English subtitles downloaded from opensubtitles with a score of 95.1%.",
+			want: SubtitleNotification{Kind: "movie", Title: "The Matrix", Year: 1999, Language: "English",
+				Action: "downloaded"},
+			ok: true,
+		},
+		{
+			name: "movie without year",
+			body: "Heat : French HI subtitles upgraded from podnapisi with a score of 90%.",
+			want: SubtitleNotification{Kind: "movie", Title: "Heat", Language: "French", HI: true, Action: "upgraded"},
+			ok:   true,
+		},
+		{
+			name: "episode",
+			body: "Breaking Bad (2008) - S01E02 - Cat's in the Bag... : English forced subtitles downloaded from " +
+				"opensubtitles with a score of 97.5%.",
+			want: SubtitleNotification{Kind: "episode", Title: "Breaking Bad", Year: 2008, Season: 1, Episode: 2,
+				EpisodeTitle: "Cat's in the Bag...", Language: "English", Forced: true, Action: "downloaded"},
+			ok: true,
+		},
+		{
+			name: "manual download",
+			body: "Dark - S02E10 - Lost and Found : German subtitles manually downloaded from subscene with a score of 88%.",
+			want: SubtitleNotification{Kind: "episode", Title: "Dark", Season: 2, Episode: 10,
+				EpisodeTitle: "Lost and Found", Language: "German", Action: "manually downloaded"},
+			ok: true,
+		},
+		{
+			name: "title containing the separator",
+			body: "Star Wars : Episode IV (1977) : Spanish subtitles uploaded.",
+			want: SubtitleNotification{Kind: "movie", Title: "Star Wars : Episode IV", Year: 1977, Language: "Spanish",
+				Action: "uploaded"},
+			ok: true,
+		},
+		{
+			name: "deletion",
+			body: "The Matrix (1999) : English subtitles deleted from disk.",
+		},
+		{
+			name: "test message",
+			body: "This is a test notification from Bazarr.",
+		},
+		{
+			name: "empty",
+			body: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseNotification(tt.body)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("ParseNotification(%q) = %+v, %v; want %+v, %v", tt.body, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
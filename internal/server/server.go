@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed openapi.yaml
+var openapiDoc []byte
+
+// Server is the embedded HTTP server of the scheduler daemon.
+type Server struct {
+	controller Controller
+	mux        *http.ServeMux
+	http       *http.Server
+	events     *broker
+
+	mu    sync.RWMutex
+	token string
+}
+
+// New creates a server for the control API and the dashboard. When token is
+// not empty every API request except the OpenAPI document must carry it.
+func New(listen string, token string, controller Controller) *Server {
+	s := &Server{
+		controller: controller,
+		mux:        http.NewServeMux(),
+		events:     newBroker(),
+		token:      token,
+	}
+	s.http = &http.Server{
+		Addr:              listen,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.http.RegisterOnShutdown(s.events.close)
+	s.routes()
+	return s
+}
+
+// Start listens on the configured address and serves in the background.
+// Errors binding the address are returned directly.
+func (s *Server) Start() (net.Addr, error) {
+	listener, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return nil, err
+	}
+	go s.http.Serve(listener)
+	return listener.Addr(), nil
+}
+
+// Shutdown stops the server, waiting up to five seconds for open requests.
+func (s *Server) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.http.Shutdown(ctx)
+}
+
+// SetToken replaces the auth token, for example after a config reload.
+func (s *Server) SetToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+// Handle registers an additional handler that requires the auth token.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, s.authenticated(handler))
+}
+
+// HandlePublic registers an additional handler that does not require the
+// auth token.
+func (s *Server) HandlePublic(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// authenticated rejects requests without the token. It is accepted as a
+// bearer token, in the X-Api-Key header, as the password of basic auth (as
+// sent by Radarr and Sonarr webhooks), or as a token query parameter for
+// clients such as EventSource that cannot set headers.
+func (s *Server) authenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		token := s.token
+		s.mu.RUnlock()
+
+		if token != "" {
+			given := r.Header.Get("X-Api-Key")
+			if bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+				given = bearer
+			} else if _, password, ok := r.BasicAuth(); ok {
+				given = password
+			}
+			if given == "" {
+				given = r.URL.Query().Get("token")
+			}
+			if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "missing or invalid token")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
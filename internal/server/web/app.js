@@ -0,0 +1,249 @@
+"use strict";
+
+// The token is kept in the browser and sent with every API request
+let token = localStorage.getItem("bazarr-sync-token") || "";
+
+const $ = (id) => document.getElementById(id);
+
+function askToken() {
+  const value = prompt("Control API token (Server.Token in config.yaml):", token);
+  if (value === null) return;
+  token = value.trim();
+  localStorage.setItem("bazarr-sync-token", token);
+  connectEvents();
+  refresh();
+}
+
+async function api(path, options = {}) {
+  const headers = { "Content-Type": "application/json" };
+  if (token) headers["Authorization"] = "Bearer " + token;
+  const response = await fetch(path, { ...options, headers });
+  if (response.status === 401) {
+    setConnection("token required", "bad");
+    throw new Error("unauthorized");
+  }
+  const body = await response.json();
+  if (!response.ok && response.status !== 404) {
+    throw new Error(body.error || response.statusText);
+  }
+  return response.ok ? body : null;
+}
+
+function setConnection(text, kind) {
+  const badge = $("connection");
+  badge.textContent = text;
+  badge.className = "badge " + (kind || "");
+}
+
+function formatTime(value) {
+  if (!value || value.startsWith("0001")) return "–";
+  return new Date(value).toLocaleString();
+}
+
+function cell(text, className) {
+  const td = document.createElement("td");
+  td.textContent = text;
+  if (className) td.className = className;
+  return td;
+}
+
+function button(label, onClick, className) {
+  const b = document.createElement("button");
+  b.textContent = label;
+  if (className) b.className = className;
+  b.addEventListener("click", onClick);
+  const td = document.createElement("td");
+  td.appendChild(b);
+  return td;
+}
+
+function renderSummary(summary) {
+  $("current-summary").innerHTML = "";
+  for (const [label, value] of [
+    ["✅ synced", summary.success],
+    ["✓ in sync", summary.already_synced],
+    ["⏭️ skipped", summary.skipped],
+    ["❌ failed", summary.failed],
+  ]) {
+    const span = document.createElement("span");
+    span.textContent = `${label}: ${value}`;
+    $("current-summary").appendChild(span);
+  }
+}
+
+// Live state of the current run, updated from the event stream
+let current = null;
+
+function renderCurrent() {
+  $("current-idle").hidden = current !== null;
+  $("current-run").hidden = current === null;
+  if (current === null) return;
+
+  const name = current.job ? `${current.job} (${current.trigger})` : current.trigger;
+  $("current-title").textContent = `${name} – ${current.progress.part || "starting"}`;
+  $("current-progress").max = current.progress.total || 1;
+  $("current-progress").value = current.progress.position || 0;
+  $("current-item").textContent = current.progress.total
+    ? `${current.progress.position}/${current.progress.total} ${current.progress.current}`
+    : "";
+  renderSummary(current.summary);
+}
+
+async function loadCurrent() {
+  current = await api("/api/runs/current");
+  renderCurrent();
+}
+
+async function loadJobs() {
+  const jobs = await api("/api/jobs");
+  const body = $("jobs");
+  body.innerHTML = "";
+  for (const job of jobs) {
+    const tr = document.createElement("tr");
+    tr.append(
+      cell(job.name),
+      cell(job.schedule),
+      cell(formatTime(job.next_run)),
+      button("Run now", () => api(`/api/jobs/${encodeURIComponent(job.name)}/run`, { method: "POST" }).then(refresh)),
+    );
+    body.appendChild(tr);
+  }
+}
+
+async function loadRuns() {
+  const runs = await api("/api/runs");
+  const body = $("runs");
+  body.innerHTML = "";
+  for (const run of runs) {
+    const tr = document.createElement("tr");
+    tr.append(
+      cell(formatTime(run.started || run.queued)),
+      cell(run.job ? `${run.job} (${run.trigger})` : run.trigger),
+      cell(run.status, "status-" + run.status),
+      cell(run.summary.success),
+      cell(run.summary.already_synced),
+      cell(run.summary.skipped),
+      cell(run.summary.failed),
+    );
+    body.appendChild(tr);
+  }
+}
+
+let failures = [];
+
+async function loadFailures() {
+  failures = await api("/api/failures");
+  renderFailures();
+}
+
+function renderFailures() {
+  const query = $("failure-search").value.toLowerCase();
+  const body = $("failures");
+  body.innerHTML = "";
+  for (const failure of failures) {
+    const text = `${failure.title} ${failure.path} ${failure.message}`.toLowerCase();
+    if (query && !text.includes(query)) continue;
+    const tr = document.createElement("tr");
+    tr.title = failure.path;
+    tr.append(
+      cell(failure.title),
+      cell(failure.language),
+      cell(failure.message, "error"),
+      cell(formatTime(failure.time)),
+      button("Retry", () => api("/api/failures/retry", {
+        method: "POST",
+        body: JSON.stringify({ path: failure.path }),
+      }).then(refresh)),
+    );
+    body.appendChild(tr);
+  }
+}
+
+let cache = { movies: [], shows: [] };
+
+async function loadCache() {
+  cache = await api("/api/cache");
+  renderCache();
+}
+
+function renderCache() {
+  const paths = cache[$("cache-kind").value] || [];
+  const query = $("cache-search").value.toLowerCase();
+  const shown = paths.filter((path) => !query || path.toLowerCase().includes(query));
+  $("cache-count").textContent = cache.enabled
+    ? `${shown.length} of ${paths.length} subtitles`
+    : "Cache is disabled";
+
+  const list = $("cache");
+  list.innerHTML = "";
+  // Rendering tens of thousands of rows freezes the page
+  for (const path of shown.slice(0, 500)) {
+    const li = document.createElement("li");
+    li.textContent = path;
+    list.appendChild(li);
+  }
+}
+
+async function refresh() {
+  try {
+    await Promise.all([loadCurrent(), loadJobs(), loadRuns(), loadFailures(), loadCache()]);
+  } catch (err) {
+    console.error(err);
+  }
+}
+
+// Several events usually arrive together, so refreshes are batched
+let refreshTimer = null;
+
+function scheduleRefresh() {
+  if (refreshTimer) return;
+  refreshTimer = setTimeout(() => {
+    refreshTimer = null;
+    refresh();
+  }, 500);
+}
+
+function handleEvent(ev) {
+  switch (ev.type) {
+    case "item":
+      if (current && current.id === ev.run_id) {
+        current.progress = { part: ev.part, position: ev.position, total: ev.total, current: ev.title };
+        renderCurrent();
+      }
+      break;
+    case "outcome":
+    case "skipped":
+      if (current && current.id === ev.run_id) {
+        const key = ev.type === "skipped" ? "skipped" : ev.outcome;
+        current.summary[key] = (current.summary[key] || 0) + 1;
+        renderCurrent();
+      }
+      if (ev.outcome === "failed" || ev.outcome === "success") scheduleRefresh();
+      break;
+    default:
+      // Runs and parts starting or finishing
+      scheduleRefresh();
+  }
+}
+
+let events = null;
+
+function connectEvents() {
+  if (events) events.close();
+  const query = token ? "?token=" + encodeURIComponent(token) : "";
+  events = new EventSource("/api/events" + query);
+  events.onopen = () => setConnection("live", "ok");
+  events.onerror = () => setConnection("reconnecting…", "bad");
+  events.onmessage = (message) => handleEvent(JSON.parse(message.data));
+}
+
+$("set-token").addEventListener("click", askToken);
+$("cancel").addEventListener("click", () => api("/api/runs/current/cancel", { method: "POST" }).then(refresh));
+$("failure-search").addEventListener("input", renderFailures);
+$("cache-search").addEventListener("input", renderCache);
+$("cache-kind").addEventListener("change", renderCache);
+
+connectEvents();
+refresh();
+// Next run times move on even when nothing happens
+setInterval(refresh, 60000);
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ImportEvent is a file imported by Radarr or Sonarr, as reported by their
+// webhook connection.
+type ImportEvent struct {
+	Source string // "radarr" or "sonarr"
+	Kind   string // "movie" or "episode", as used by the sync API
+	// Radarr movie ID or Sonarr episode IDs
+	Ids      []int
+	SeriesId int
+	Title    string
+	// The import replaced an existing file
+	Upgrade bool
+}
+
+// Fields of the Radarr and Sonarr webhook payloads that are used
+type arrPayload struct {
+	EventType string `json:"eventType"`
+	IsUpgrade bool   `json:"isUpgrade"`
+	Movie     struct {
+		Id    int    `json:"id"`
+		Title string `json:"title"`
+	} `json:"movie"`
+	Series struct {
+		Id    int    `json:"id"`
+		Title string `json:"title"`
+	} `json:"series"`
+	Episodes []struct {
+		Id int `json:"id"`
+	} `json:"episodes"`
+}
+
+func (s *Server) routeWebhooks() {
+	s.Handle("POST /api/webhooks/radarr", http.HandlerFunc(s.arrWebhook("radarr")))
+	s.Handle("POST /api/webhooks/sonarr", http.HandlerFunc(s.arrWebhook("sonarr")))
+}
+
+// arrWebhook accepts the payloads of a Radarr or Sonarr webhook connection.
+// Only "Download" events, sent on import and on upgrade, start a sync; the
+// "Test" event and all others are acknowledged and ignored.
+func (s *Server) arrWebhook(source string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload arrPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+		if payload.EventType != "Download" {
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ignored", "event": payload.EventType})
+			return
+		}
+
+		ev := ImportEvent{Source: source, Upgrade: payload.IsUpgrade}
+		if source == "radarr" {
+			ev.Kind = "movie"
+			ev.Title = payload.Movie.Title
+			if payload.Movie.Id != 0 {
+				ev.Ids = []int{payload.Movie.Id}
+			}
+		} else {
+			ev.Kind = "episode"
+			ev.Title = payload.Series.Title
+			ev.SeriesId = payload.Series.Id
+			for _, episode := range payload.Episodes {
+				ev.Ids = append(ev.Ids, episode.Id)
+			}
+		}
+
+		err := s.controller.Imported(ev)
+		if errors.Is(err, ErrInvalidRequest) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "scheduled"})
+	}
+}
@@ -0,0 +1,187 @@
+// Package socketio is a minimal Socket.IO v5 client over Engine.IO v4 HTTP
+// long-polling. It only receives events, which is all that is needed to
+// follow Bazarr's live updates.
+package socketio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrClosed is returned when the server closes the session.
+var ErrClosed = errors.New("socket.io session closed by server")
+
+// Engine.IO packets in a polling payload are separated by this character
+const recordSeparator = "\x1e"
+
+// Client connects to one Socket.IO endpoint.
+type Client struct {
+	// Endpoint, for example "http://localhost:6767/api/socket.io/"
+	URL string
+	// Sent with every request, for example an API key
+	Header http.Header
+}
+
+type handshake struct {
+	Sid          string `json:"sid"`
+	PingInterval int    `json:"pingInterval"`
+	PingTimeout  int    `json:"pingTimeout"`
+}
+
+// session is one Engine.IO connection.
+type session struct {
+	client *Client
+	http   *http.Client
+	sid    string
+}
+
+// Listen connects to the default namespace and calls onEvent for every event
+// the server emits, with the event name and its JSON arguments. onConnect is
+// called once the namespace is joined. Listen returns when ctx is done or
+// the connection fails; reconnecting is left to the caller.
+func (c *Client) Listen(ctx context.Context, onConnect func(), onEvent func(name string, args []json.RawMessage)) error {
+	s := &session{client: c, http: &http.Client{Timeout: 30 * time.Second}}
+
+	packets, err := s.get(ctx)
+	if err != nil {
+		return err
+	}
+	if len(packets) == 0 || !strings.HasPrefix(packets[0], "0") {
+		return fmt.Errorf("unexpected handshake %q", strings.Join(packets, recordSeparator))
+	}
+	var hs handshake
+	if err := json.Unmarshal([]byte(packets[0][1:]), &hs); err != nil {
+		return fmt.Errorf("invalid handshake: %w", err)
+	}
+	s.sid = hs.Sid
+	// A poll is held open by the server for up to the ping interval
+	s.http.Timeout = time.Duration(hs.PingInterval+hs.PingTimeout)*time.Millisecond + 5*time.Second
+
+	// Join the default namespace
+	if err := s.post(ctx, "40"); err != nil {
+		return err
+	}
+
+	for {
+		packets, err := s.get(ctx)
+		if err != nil {
+			return err
+		}
+		for _, packet := range packets {
+			if packet == "" {
+				continue
+			}
+			switch packet[0] {
+			case '1':
+				return ErrClosed
+			case '2':
+				if err := s.post(ctx, "3"); err != nil {
+					return err
+				}
+			case '4':
+				if err := handleMessage(packet[1:], onConnect, onEvent); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// handleMessage handles a Socket.IO packet carried in an Engine.IO message.
+func handleMessage(packet string, onConnect func(), onEvent func(name string, args []json.RawMessage)) error {
+	if packet == "" {
+		return nil
+	}
+	kind, data := packet[0], packet[1:]
+	// Packets for other namespaces start with "/name,"
+	if strings.HasPrefix(data, "/") {
+		return nil
+	}
+
+	switch kind {
+	case '0':
+		if onConnect != nil {
+			onConnect()
+		}
+	case '1':
+		return ErrClosed
+	case '2':
+		// An acknowledgement ID may precede the arguments
+		data = strings.TrimLeft(data, "0123456789")
+		var args []json.RawMessage
+		if err := json.Unmarshal([]byte(data), &args); err != nil || len(args) == 0 {
+			return nil
+		}
+		var name string
+		if err := json.Unmarshal(args[0], &name); err != nil {
+			return nil
+		}
+		onEvent(name, args[1:])
+	case '4':
+		return fmt.Errorf("connection refused: %s", data)
+	}
+	return nil
+}
+
+func (s *session) url() string {
+	u, _ := url.Parse(s.client.URL)
+	query := u.Query()
+	query.Set("EIO", "4")
+	query.Set("transport", "polling")
+	query.Set("t", fmt.Sprint(time.Now().UnixNano()))
+	if s.sid != "" {
+		query.Set("sid", s.sid)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// get polls for packets, blocking until the server has something to send.
+func (s *session) get(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.url(), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(body, recordSeparator), nil
+}
+
+func (s *session) post(ctx context.Context, packet string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.url(), strings.NewReader(packet))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain;charset=UTF-8")
+	_, err = s.do(req)
+	return err
+}
+
+func (s *session) do(req *http.Request) (string, error) {
+	for key, values := range s.client.Header {
+		req.Header[key] = values
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: status %d: %s", req.Method, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
@@ -0,0 +1,118 @@
+package socketio_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/regix1/bazarr-sync/internal/socketio"
+	"github.com/regix1/bazarr-sync/internal/socketio/socketiotest"
+)
+
+type event struct {
+	name string
+	args []json.RawMessage
+}
+
+// listen starts a client of srv and returns its events and the error Listen
+// returned.
+func listen(t *testing.T, srv *socketiotest.Server) (<-chan event, <-chan error) {
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	events := make(chan event, 16)
+	done := make(chan error, 1)
+	c := &socketio.Client{URL: ts.URL + "/socket.io/"}
+	go func() {
+		done <- c.Listen(ctx, nil, func(name string, args []json.RawMessage) {
+			events <- event{name, args}
+		})
+	}()
+
+	select {
+	case <-srv.Joins():
+	case err := <-done:
+		t.Fatalf("Listen returned before joining: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not join the namespace")
+	}
+	return events, done
+}
+
+func TestListenReceivesEvents(t *testing.T) {
+	srv := socketiotest.NewServer()
+	events, _ := listen(t, srv)
+
+	if err := srv.Emit("data", map[string]any{"type": "movie", "action": "update", "payload": 12}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-events:
+		if ev.name != "data" || len(ev.args) != 1 {
+			t.Fatalf("event = %s %s, want data with one argument", ev.name, ev.args)
+		}
+		var payload struct {
+			Type    string `json:"type"`
+			Action  string `json:"action"`
+			Payload int    `json:"payload"`
+		}
+		if err := json.Unmarshal(ev.args[0], &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.Type != "movie" || payload.Action != "update" || payload.Payload != 12 {
+			t.Errorf("payload = %+v, want a movie update of 12", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+}
+
+func TestListenAnswersPings(t *testing.T) {
+	srv := socketiotest.NewServer()
+	listen(t, srv)
+
+	srv.Send("2")
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Contains(srv.Received(), "3") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no pong received, got %q", srv.Received())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestListenReturnsWhenClosed(t *testing.T) {
+	srv := socketiotest.NewServer()
+	_, done := listen(t, srv)
+
+	srv.Send("1")
+	select {
+	case err := <-done:
+		if !errors.Is(err, socketio.ErrClosed) {
+			t.Errorf("Listen returned %v, want ErrClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Listen did not return after the close packet")
+	}
+}
+
+func TestListenReturnsWhenDropped(t *testing.T) {
+	srv := socketiotest.NewServer()
+	_, done := listen(t, srv)
+
+	srv.Drop()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Listen returned nil after the session was dropped")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Listen did not return after the session was dropped")
+	}
+}
@@ -0,0 +1,174 @@
+// Package socketiotest provides a Socket.IO server over Engine.IO v4 HTTP
+// long-polling for tests. It is an http.Handler, to serve with
+// net/http/httptest.
+package socketiotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Engine.IO packets in a polling payload are separated by this character
+const recordSeparator = "\x1e"
+
+// How long a poll is held open before the server answers with a noop
+const pollTimeout = time.Second
+
+// Server is a Socket.IO server that emits what the test asks it to.
+type Server struct {
+	// APIKey, when set, must be sent as the apikey query parameter
+	APIKey string
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	nextId   int
+	received []string
+	joins    chan struct{}
+}
+
+// session is one Engine.IO connection.
+type session struct {
+	joined  bool
+	packets chan string
+	closed  chan struct{}
+}
+
+// NewServer returns a Server without sessions.
+func NewServer() *Server {
+	return &Server{sessions: make(map[string]*session), joins: make(chan struct{}, 16)}
+}
+
+// Joins receives a value every time a client joins the default namespace.
+func (s *Server) Joins() <-chan struct{} {
+	return s.joins
+}
+
+// Emit sends an event to every client that joined the default namespace.
+func (s *Server) Emit(name string, args ...any) error {
+	payload, err := json.Marshal(append([]any{name}, args...))
+	if err != nil {
+		return err
+	}
+	s.Send("42" + string(payload))
+	return nil
+}
+
+// Send sends a raw Engine.IO packet, such as "2" for a ping or "1" to close,
+// to every client that joined the default namespace.
+func (s *Server) Send(packet string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sess := range s.sessions {
+		if sess.joined {
+			sess.packets <- packet
+		}
+	}
+}
+
+// Drop ends every session without a close packet, as a restarting server
+// would. Pending and later polls of those sessions fail.
+func (s *Server) Drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sid, sess := range s.sessions {
+		close(sess.closed)
+		delete(s.sessions, sid)
+	}
+}
+
+// Received returns the packets clients posted, in order.
+func (s *Server) Received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.received...)
+}
+
+// ServeHTTP serves the Engine.IO polling endpoint.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if s.APIKey != "" && query.Get("apikey") != s.APIKey {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if query.Get("EIO") != "4" || query.Get("transport") != "polling" {
+		http.Error(w, `{"code":0,"message":"Transport unknown"}`, http.StatusBadRequest)
+		return
+	}
+
+	sid := query.Get("sid")
+	if sid == "" {
+		s.handshake(w)
+		return
+	}
+	s.mu.Lock()
+	sess := s.sessions[sid]
+	s.mu.Unlock()
+	if sess == nil {
+		http.Error(w, `{"code":1,"message":"Session ID unknown"}`, http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		s.receive(w, r, sid, sess)
+		return
+	}
+	s.poll(w, r, sess)
+}
+
+func (s *Server) handshake(w http.ResponseWriter) {
+	s.mu.Lock()
+	s.nextId++
+	sid := fmt.Sprintf("session%d", s.nextId)
+	s.sessions[sid] = &session{packets: make(chan string, 64), closed: make(chan struct{})}
+	s.mu.Unlock()
+
+	fmt.Fprintf(w, `0{"sid":%q,"upgrades":[],"pingInterval":25000,"pingTimeout":20000,"maxPayload":1000000}`, sid)
+}
+
+func (s *Server) receive(w http.ResponseWriter, r *http.Request, sid string, sess *session) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	for _, packet := range strings.Split(string(body), recordSeparator) {
+		s.received = append(s.received, packet)
+		if packet == "40" && !sess.joined {
+			sess.joined = true
+			sess.packets <- `40{"sid":"` + sid + `-io"}`
+			select {
+			case s.joins <- struct{}{}:
+			default:
+			}
+		}
+	}
+	s.mu.Unlock()
+	fmt.Fprint(w, "ok")
+}
+
+// poll answers with the queued packets, waiting for one if there are none.
+func (s *Server) poll(w http.ResponseWriter, r *http.Request, sess *session) {
+	var packets []string
+	select {
+	case packet := <-sess.packets:
+		packets = append(packets, packet)
+	case <-sess.closed:
+		http.Error(w, `{"code":1,"message":"Session ID unknown"}`, http.StatusBadRequest)
+		return
+	case <-r.Context().Done():
+		return
+	case <-time.After(pollTimeout):
+		packets = append(packets, "6")
+	}
+	for len(sess.packets) > 0 {
+		packets = append(packets, <-sess.packets)
+	}
+	fmt.Fprint(w, strings.Join(packets, recordSeparator))
+}
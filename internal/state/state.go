@@ -0,0 +1,58 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State is what bazarr-sync remembers between runs.
+type State struct {
+	// Start time of the last run that completed, incremental or full.
+	// Incremental runs pick up Bazarr history recorded after it.
+	LastRun time.Time `json:"last_run"`
+	// Start time of the last completed full library pass.
+	LastFullRun time.Time `json:"last_full_run"`
+	// Time of the newest history entry handled by the watch command.
+	WatchMark time.Time `json:"watch_mark"`
+}
+
+// Load reads the state file. A missing file is not an error and yields an
+// empty State.
+func Load(path string) (State, error) {
+	var s State
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
+
+// Save writes the state file, replacing it atomically so a crash mid-write
+// never leaves a truncated file behind.
+func Save(path string, s State) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}